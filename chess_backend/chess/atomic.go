@@ -0,0 +1,84 @@
+package chess
+
+// Atomic : chaque prise fait exploser la case d'arrivée et les pièces voisines
+// (hors pions) ; faire exploser le roi adverse gagne la partie
+type Atomic struct{ Standard }
+
+func (Atomic) Name() string { return "atomic" }
+
+func (a Atomic) LegalMoves(p *Position) []Move {
+	pseudo := p.pseudoLegalMoves(make([]Move, 0, 64))
+	legal := pseudo[:0]
+	for _, m := range pseudo {
+		// Le roi ne peut pas prendre : il exploserait
+		if m.IsCapture() && p.board[m.From].Type() == King {
+			continue
+		}
+		next := a.Play(p, m)
+		if atomicKingSafe(&next, p.turn) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+func (Atomic) Play(p *Position, m Move) Position {
+	next := p.play(m)
+	if !m.IsCapture() {
+		return next
+	}
+
+	blast := squareBB(m.To) | kingAttacks[m.To]&^next.byType[Pawn]
+	for exploded := blast & next.Occupied(); exploded != 0; {
+		sq := exploded.Pop()
+		pc := next.remove(sq)
+		next.clearCastleRook(pc.Color(), sq)
+		if pc.Type() == King {
+			next.setCastleRook(pc.Color(), kingSide, NoSquare)
+			next.setCastleRook(pc.Color(), queenSide, NoSquare)
+		}
+	}
+	return next
+}
+
+// Après un coup du camp us : son roi doit survivre et ne pas rester en prise,
+// sauf si le roi adverse a sauté ou si les deux rois se touchent
+func atomicKingSafe(p *Position, us Color) bool {
+	ours := p.KingSquare(us)
+	if ours == NoSquare {
+		return false
+	}
+	theirs := p.KingSquare(us.Other())
+	if theirs == NoSquare || kingAttacks[ours].Has(theirs) {
+		return true
+	}
+	return !p.attackedBy(ours, us.Other(), p.Occupied())
+}
+
+func (a Atomic) Outcome(p *Position) (Outcome, bool) {
+	for _, c := range []Color{White, Black} {
+		if p.KingSquare(c) == NoSquare {
+			return Outcome{Termination: KingExploded, Winner: c.Other()}, true
+		}
+	}
+
+	if len(a.LegalMoves(p)) > 0 {
+		return Outcome{}, false
+	}
+	if !atomicKingSafe(p, p.turn) {
+		return Outcome{Termination: Checkmate, Winner: p.turn.Other()}, true
+	}
+	return Outcome{Termination: Stalemate, Draw: true}, true
+}
+
+// Roi seul, ou pièce mineure seule face à un roi seul : aucune explosion possible du roi adverse
+func (Atomic) HasInsufficientMaterial(p *Position, c Color) bool {
+	own := p.byColor[c] &^ p.byType[King]
+	if own == 0 {
+		return true
+	}
+	if p.byColor[c.Other()]&^p.byType[King] != 0 {
+		return false
+	}
+	return own.Count() == 1 && own&(p.byType[Knight]|p.byType[Bishop]) != 0
+}
@@ -0,0 +1,108 @@
+package chess
+
+// Directions de glissement : les quatre premières augmentent l'index de case
+var directions = [8][2]int{
+	{0, 1}, {1, 0}, {1, 1}, {-1, 1}, // N, E, NE, NW
+	{0, -1}, {-1, 0}, {1, -1}, {-1, -1}, // S, W, SE, SW
+}
+
+var (
+	knightAttacks [64]Bitboard
+	kingAttacks   [64]Bitboard
+	pawnAttacks   [2][64]Bitboard
+	rays          [8][64]Bitboard
+	betweenBB     [64][64]Bitboard
+)
+
+func init() {
+	for sq := Square(0); sq < 64; sq++ {
+		f, r := sq.File(), sq.Rank()
+
+		for _, d := range [][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}} {
+			knightAttacks[sq] |= offsetBB(f+d[0], r+d[1])
+		}
+		for _, d := range directions {
+			kingAttacks[sq] |= offsetBB(f+d[0], r+d[1])
+		}
+		pawnAttacks[White][sq] = offsetBB(f-1, r+1) | offsetBB(f+1, r+1)
+		pawnAttacks[Black][sq] = offsetBB(f-1, r-1) | offsetBB(f+1, r-1)
+
+		for dir, d := range directions {
+			for nf, nr := f+d[0], r+d[1]; onBoard(nf, nr); nf, nr = nf+d[0], nr+d[1] {
+				rays[dir][sq] |= squareBB(NewSquare(nf, nr))
+			}
+		}
+	}
+
+	// Cases strictement comprises entre deux cases alignées
+	for a := Square(0); a < 64; a++ {
+		for dir := range directions {
+			ray := rays[dir][a]
+			for r := ray; r != 0; {
+				b := r.Pop()
+				betweenBB[a][b] = ray &^ rays[dir][b] &^ squareBB(b)
+			}
+		}
+	}
+}
+
+func onBoard(file, rank int) bool {
+	return file >= 0 && file < 8 && rank >= 0 && rank < 8
+}
+
+func offsetBB(file, rank int) Bitboard {
+	if !onBoard(file, rank) {
+		return 0
+	}
+	return squareBB(NewSquare(file, rank))
+}
+
+func slidingAttacks(sq Square, occupied Bitboard, dirs []int) Bitboard {
+	var attacks Bitboard
+	for _, dir := range dirs {
+		ray := rays[dir][sq]
+		if blockers := ray & occupied; blockers != 0 {
+			var blocker Square
+			if dir < 4 {
+				blocker = blockers.First()
+			} else {
+				blocker = blockers.last()
+			}
+			ray &^= rays[dir][blocker]
+		}
+		attacks |= ray
+	}
+	return attacks
+}
+
+var (
+	rookDirs   = []int{0, 1, 4, 5}
+	bishopDirs = []int{2, 3, 6, 7}
+)
+
+func rookAttacks(sq Square, occupied Bitboard) Bitboard {
+	return slidingAttacks(sq, occupied, rookDirs)
+}
+
+func bishopAttacks(sq Square, occupied Bitboard) Bitboard {
+	return slidingAttacks(sq, occupied, bishopDirs)
+}
+
+// Cases attaquées par une pièce de type pt posée sur sq
+func pieceAttacks(pt PieceType, c Color, sq Square, occupied Bitboard) Bitboard {
+	switch pt {
+	case Pawn:
+		return pawnAttacks[c][sq]
+	case Knight:
+		return knightAttacks[sq]
+	case Bishop:
+		return bishopAttacks(sq, occupied)
+	case Rook:
+		return rookAttacks(sq, occupied)
+	case Queen:
+		return bishopAttacks(sq, occupied) | rookAttacks(sq, occupied)
+	case King:
+		return kingAttacks[sq]
+	}
+	return 0
+}
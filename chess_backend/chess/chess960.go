@@ -0,0 +1,65 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Paires de cases des cavaliers parmi les cinq cases restantes (numérotation de Scharnagl)
+var chess960Knights = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// FEN de la position de départ Chess960 numéro n (0 à 959, 518 = position classique)
+func Chess960FEN(n int) (string, error) {
+	if n < 0 || n >= 960 {
+		return "", fmt.Errorf("%w: chess960 position %d out of range", ErrInvalidFEN, n)
+	}
+
+	var rank [8]byte
+	rank[2*(n%4)+1] = 'b'
+	n /= 4
+	rank[2*(n%4)] = 'b'
+	n /= 4
+
+	// Placer une pièce sur la i-ème case encore libre
+	place := func(piece byte, i int) {
+		for file := range rank {
+			if rank[file] != 0 {
+				continue
+			}
+			if i == 0 {
+				rank[file] = piece
+				return
+			}
+			i--
+		}
+	}
+	place('q', n%6)
+	n /= 6
+	knights := chess960Knights[n]
+	place('n', knights[1])
+	place('n', knights[0])
+	place('r', 0)
+	place('k', 0)
+	place('r', 0)
+
+	black := string(rank[:])
+	white := strings.ToUpper(black)
+	return fmt.Sprintf("%s/pppppppp/8/8/8/8/PPPPPPPP/%s w KQkq - 0 1", black, white), nil
+}
+
+// Partie Chess960 depuis une FEN (X-FEN ou Shredder-FEN)
+func NewChess960Game(fen string) (*Game, error) {
+	game, err := NewGame(fen)
+	if err != nil {
+		return nil, err
+	}
+	game.positions[0].chess960 = true
+	return game, nil
+}
+
+// Indique si les roques suivent les règles d'écriture du Chess960
+func (p *Position) IsChess960() bool {
+	return p.chess960
+}
@@ -0,0 +1,195 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ordre des pièces dans la réserve écrite en FEN
+var pocketOrder = []PieceType{Queen, Rook, Bishop, Knight, Pawn}
+
+// Crazyhouse : les pièces prises rejoignent la réserve du preneur
+// et peuvent être parachutées sur une case libre à la place d'un coup
+type Crazyhouse struct{ Standard }
+
+func (Crazyhouse) Name() string { return "crazyhouse" }
+func (Crazyhouse) StartingFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+}
+
+func (c Crazyhouse) LegalMoves(p *Position) []Move {
+	moves := p.legalMoves()
+	us := p.turn
+	inCheck := p.InCheck()
+
+	for _, pt := range pocketOrder {
+		if p.pockets[us][pt] == 0 {
+			continue
+		}
+		targets := ^p.Occupied()
+		if pt == Pawn {
+			targets &^= rank1 | rank8
+		}
+		for targets != 0 {
+			m := Move{From: NoSquare, To: targets.Pop(), Drop: pt}
+			// Hors échec, un parachutage ne peut pas exposer le roi
+			if inCheck {
+				next := c.Play(p, m)
+				if next.IsAttacked(next.KingSquare(us), us.Other()) {
+					continue
+				}
+			}
+			moves = append(moves, m)
+		}
+	}
+	return moves
+}
+
+func (Crazyhouse) Play(p *Position, m Move) Position {
+	return playWithPockets(p, m, true)
+}
+
+// Jouer un coup en tenant les réserves ; keepCaptures ajoute la pièce prise à la réserve du preneur
+func playWithPockets(p *Position, m Move, keepCaptures bool) Position {
+	us := p.turn
+	if m.IsDrop() {
+		next := *p
+		next.setEnPassant(NoSquare)
+		next.put(NewPiece(us, m.Drop), m.To)
+		next.setPocket(us, m.Drop, next.pockets[us][m.Drop]-1)
+		next.halfmove = 0
+		if us == Black {
+			next.fullmove++
+		}
+		next.turn = us.Other()
+		next.hash ^= zobristTurn
+		return next
+	}
+
+	captured := p.CapturedPiece(m)
+	next := p.play(m)
+	next.promoted &^= squareBB(m.From) | squareBB(m.To)
+	if m.Promotion != NoPieceType || (p.promoted.Has(m.From) && !m.IsCastle()) {
+		next.promoted |= squareBB(m.To)
+	}
+	if keepCaptures && captured != NoPieceType {
+		next.setPocket(us, captured, next.pockets[us][captured]+1)
+	}
+	return next
+}
+
+// Pièce gagnée pour la réserve en jouant m (NoPieceType sans prise) :
+// une pièce promue redevient un pion
+func (p *Position) CapturedPiece(m Move) PieceType {
+	switch {
+	case m.Flags&FlagEnPassant != 0:
+		return Pawn
+	case m.IsCapture():
+		if p.promoted.Has(m.To) {
+			return Pawn
+		}
+		return p.board[m.To].Type()
+	}
+	return NoPieceType
+}
+
+// Bughouse : Crazyhouse à deux échiquiers, les pièces prises passent
+// au partenaire qui joue l'autre couleur sur l'échiquier voisin
+type Bughouse struct{ Crazyhouse }
+
+func (Bughouse) Name() string { return "bughouse" }
+
+func (Bughouse) Play(p *Position, m Move) Position {
+	return playWithPockets(p, m, false)
+}
+
+// Indique si la variante se joue avec des réserves de pièces
+func HasPockets(v Variant) bool {
+	switch v.(type) {
+	case Crazyhouse, Bughouse:
+		return true
+	}
+	return false
+}
+
+// Une pièce prise peut toujours revenir sur l'échiquier
+func (Crazyhouse) HasInsufficientMaterial(p *Position, c Color) bool {
+	return false
+}
+
+// Réserve entre crochets ("RNBQKBNR[Qn]") ou en neuvième rangée, pièces promues suivies de "~"
+func (Crazyhouse) ParseFENFields(p *Position, fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return fields, nil
+	}
+	placement, pocket := fields[0], ""
+	if open := strings.IndexByte(placement, '['); open >= 0 {
+		if !strings.HasSuffix(placement, "]") {
+			return nil, fmt.Errorf("%w: unterminated pocket", ErrInvalidFEN)
+		}
+		placement, pocket = placement[:open], placement[open+1:len(placement)-1]
+	} else if ranks := strings.Split(placement, "/"); len(ranks) == 9 {
+		placement, pocket = strings.Join(ranks[:8], "/"), ranks[8]
+	}
+
+	for i := 0; i < len(pocket); i++ {
+		pc := pieceFromLetter(pocket[i])
+		if pc == NoPiece || pc.Type() == King {
+			return nil, fmt.Errorf("%w: invalid pocket piece %q", ErrInvalidFEN, pocket[i])
+		}
+		p.setPocket(pc.Color(), pc.Type(), p.pockets[pc.Color()][pc.Type()]+1)
+	}
+
+	// Repérer les pièces promues puis retirer les marques
+	rank, file := 7, 0
+	for i := 0; i < len(placement); i++ {
+		switch c := placement[i]; {
+		case c == '/':
+			rank, file = rank-1, 0
+		case c == '~':
+			if file > 0 && rank >= 0 {
+				p.promoted |= squareBB(NewSquare(file-1, rank))
+			}
+		case c >= '1' && c <= '8':
+			file += int(c - '0')
+		default:
+			file++
+		}
+	}
+
+	rest := append([]string{strings.ReplaceAll(placement, "~", "")}, fields[1:]...)
+	return rest, nil
+}
+
+func (Crazyhouse) FormatFENFields(p *Position, fields []string) []string {
+	// Réinsérer les marques de promotion case par case
+	var marked strings.Builder
+	rank, file := 7, 0
+	for i := 0; i < len(fields[0]); i++ {
+		c := fields[0][i]
+		marked.WriteByte(c)
+		switch {
+		case c == '/':
+			rank, file = rank-1, 0
+		case c >= '1' && c <= '8':
+			file += int(c - '0')
+		default:
+			if p.promoted.Has(NewSquare(file, rank)) {
+				marked.WriteByte('~')
+			}
+			file++
+		}
+	}
+
+	marked.WriteByte('[')
+	for _, color := range []Color{White, Black} {
+		for _, pt := range pocketOrder {
+			for n := 0; n < p.pockets[color][pt]; n++ {
+				marked.WriteByte(NewPiece(color, pt).Letter())
+			}
+		}
+	}
+	marked.WriteByte(']')
+
+	return append([]string{marked.String()}, fields[1:]...)
+}
@@ -0,0 +1,326 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const StartingFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+var (
+	ErrInvalidFEN  = errors.New("invalid fen")
+	ErrIllegalMove = errors.New("illegal move")
+)
+
+// Charger une position à partir d'une FEN (les compteurs sont optionnels)
+func ParseFEN(fen string) (*Position, error) {
+	return parseFEN(fen, nil)
+}
+
+// Charger une position d'une variante, avec ses champs FEN supplémentaires
+func ParseVariantFEN(v Variant, fen string) (*Position, error) {
+	return parseFEN(fen, v)
+}
+
+func parseFEN(fen string, v Variant) (*Position, error) {
+	fields := strings.Fields(fen)
+	p := &Position{epSquare: NoSquare, fullmove: 1, variant: v}
+	if v != nil {
+		var err error
+		if fields, err = v.ParseFENFields(p, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(fields) < 4 || len(fields) > 6 {
+		return nil, fmt.Errorf("%w: expected 4 to 6 fields, got %d", ErrInvalidFEN, len(fields))
+	}
+
+	for c := range p.castleRooks {
+		p.castleRooks[c] = [2]Square{NoSquare, NoSquare}
+	}
+
+	if err := p.parsePlacement(fields[0]); err != nil {
+		return nil, err
+	}
+
+	switch fields[1] {
+	case "w":
+		p.turn = White
+	case "b":
+		p.turn = Black
+		p.hash ^= zobristTurn
+	default:
+		return nil, fmt.Errorf("%w: invalid side to move %q", ErrInvalidFEN, fields[1])
+	}
+
+	if err := p.parseCastling(fields[2]); err != nil {
+		return nil, err
+	}
+
+	if fields[3] != "-" {
+		ep, err := ParseSquare(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFEN, err)
+		}
+		// Ignorer la case en passant si aucune prise n'est possible
+		if pawnAttacks[p.turn.Other()][ep]&p.Pieces(p.turn, Pawn) != 0 {
+			p.setEnPassant(ep)
+		}
+	}
+
+	if len(fields) > 4 {
+		n, err := strconv.Atoi(fields[4])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: invalid halfmove clock %q", ErrInvalidFEN, fields[4])
+		}
+		p.halfmove = n
+	}
+	if len(fields) > 5 {
+		n, err := strconv.Atoi(fields[5])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: invalid fullmove number %q", ErrInvalidFEN, fields[5])
+		}
+		p.fullmove = n
+	}
+
+	return p, nil
+}
+
+func (p *Position) parsePlacement(placement string) error {
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return fmt.Errorf("%w: expected 8 ranks, got %d", ErrInvalidFEN, len(ranks))
+	}
+	for i, row := range ranks {
+		rank, file := 7-i, 0
+		for j := 0; j < len(row); j++ {
+			c := row[j]
+			if c >= '1' && c <= '8' {
+				file += int(c - '0')
+				continue
+			}
+			pc := pieceFromLetter(c)
+			if pc == NoPiece {
+				return fmt.Errorf("%w: invalid piece %q", ErrInvalidFEN, c)
+			}
+			if file > 7 {
+				return fmt.Errorf("%w: rank %d too long", ErrInvalidFEN, rank+1)
+			}
+			p.put(pc, NewSquare(file, rank))
+			file++
+		}
+		if file != 8 {
+			return fmt.Errorf("%w: rank %d has %d files", ErrInvalidFEN, rank+1, file)
+		}
+	}
+
+	for _, c := range []Color{White, Black} {
+		if n := p.Pieces(c, King).Count(); n != 1 {
+			return fmt.Errorf("%w: %s has %d kings", ErrInvalidFEN, c, n)
+		}
+	}
+	return nil
+}
+
+// Droits de roque : KQkq (tour la plus extérieure) ou lettres de colonne (Shredder/X-FEN)
+func (p *Position) parseCastling(castling string) error {
+	if castling == "-" {
+		return nil
+	}
+	for i := 0; i < len(castling); i++ {
+		c := castling[i]
+		color, rank := White, 0
+		if c >= 'a' && c <= 'z' {
+			color, rank = Black, 7
+		}
+		king := p.KingSquare(color)
+		if king.Rank() != rank {
+			return fmt.Errorf("%w: castling right %q without king on back rank", ErrInvalidFEN, c)
+		}
+		rooks := p.Pieces(color, Rook) & (rank1 << uint(8*rank))
+
+		var rook Square
+		switch lower := c | 0x20; {
+		case lower == 'k':
+			rook = (rooks &^ (squareBB(king+1) - 1)).last()
+		case lower == 'q':
+			rook = (rooks & (squareBB(king) - 1)).First()
+		case lower >= 'a' && lower <= 'h':
+			rook = NewSquare(int(lower-'a'), rank)
+			if !rooks.Has(rook) {
+				rook = NoSquare
+			}
+		default:
+			return fmt.Errorf("%w: invalid castling field %q", ErrInvalidFEN, castling)
+		}
+		if rook == NoSquare {
+			return fmt.Errorf("%w: castling right %q without rook", ErrInvalidFEN, c)
+		}
+
+		side := queenSide
+		if rook.File() > king.File() {
+			side = kingSide
+		}
+		p.setCastleRook(color, side, rook)
+
+		// Roi ou tour hors de leurs cases classiques : position Chess960
+		if king.File() != 4 || (rook.File() != 0 && rook.File() != 7) {
+			p.chess960 = true
+		}
+	}
+	return nil
+}
+
+// Sérialiser la position en FEN
+func (p *Position) FEN() string {
+	var sb strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file < 8; file++ {
+			pc := p.board[NewSquare(file, rank)]
+			if pc == NoPiece {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteByte(pc.Letter())
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank > 0 {
+			sb.WriteByte('/')
+		}
+	}
+
+	if p.turn == White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+
+	sb.WriteString(p.castlingString())
+	sb.WriteByte(' ')
+	sb.WriteString(p.epSquare.String())
+	fmt.Fprintf(&sb, " %d %d", p.halfmove, p.fullmove)
+	if p.variant == nil {
+		return sb.String()
+	}
+	return strings.Join(p.variant.FormatFENFields(p, strings.Fields(sb.String())), " ")
+}
+
+func (p *Position) castlingString() string {
+	var sb strings.Builder
+	for _, c := range []Color{White, Black} {
+		rank := 0
+		if c == Black {
+			rank = 7
+		}
+		rooks := p.Pieces(c, Rook) & (rank1 << uint(8*rank))
+		for side, letter := range []byte{'k', 'q'} {
+			rook := p.castleRooks[c][side]
+			if rook == NoSquare {
+				continue
+			}
+			// X-FEN : lettre de colonne seulement si une autre tour est plus à l'extérieur
+			outer := (rooks &^ (squareBB(rook) - 1)).last()
+			if side == queenSide {
+				outer = (rooks & (squareBB(rook+1) - 1)).First()
+			}
+			if outer != rook {
+				letter = byte('a' + rook.File())
+			}
+			if c == White {
+				letter -= 'a' - 'A'
+			}
+			sb.WriteByte(letter)
+		}
+	}
+	if sb.Len() == 0 {
+		return "-"
+	}
+	return sb.String()
+}
+
+// Charger une FEN et vérifier que la position est légale et jouable
+func ValidateFEN(fen string) (*Position, error) {
+	return validateFEN(fen, nil)
+}
+
+// Charger et vérifier la FEN d'une variante
+func ValidateVariantFEN(v Variant, fen string) (*Position, error) {
+	return validateFEN(fen, v)
+}
+
+func validateFEN(fen string, v Variant) (*Position, error) {
+	p, err := parseFEN(fen, v)
+	if err != nil {
+		return nil, err
+	}
+
+	// La case en passant est vérifiée telle qu'écrite, même si aucune prise n'est possible
+	if ep := strings.Fields(fen)[3]; ep != "-" {
+		sq, _ := ParseSquare(ep)
+		forward := 8
+		if p.turn == Black {
+			forward = -8
+		}
+		pushed, origin := sq-Square(forward), sq+Square(forward)
+		if sq.Rank() != relativeRank(p.turn, 5) || p.board[sq] != NoPiece || p.board[origin] != NoPiece ||
+			p.board[pushed] != NewPiece(p.turn.Other(), Pawn) {
+			return nil, fmt.Errorf("%w: impossible en passant square %s", ErrInvalidFEN, ep)
+		}
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Vérifier la légalité d'une position : effectifs, pions, échecs
+func (p *Position) Validate() error {
+	for _, c := range []Color{White, Black} {
+		if n := p.byColor[c].Count(); n > 16 {
+			return fmt.Errorf("%w: %s has %d pieces", ErrInvalidFEN, c, n)
+		}
+		if n := p.Pieces(c, Pawn).Count(); n > 8 {
+			return fmt.Errorf("%w: %s has %d pawns", ErrInvalidFEN, c, n)
+		}
+	}
+	if p.byType[Pawn]&(rank1|rank8) != 0 {
+		return fmt.Errorf("%w: pawn on first or last rank", ErrInvalidFEN)
+	}
+
+	// Le camp qui n'a pas le trait ne peut pas être en échec
+	if p.IsAttacked(p.KingSquare(p.turn.Other()), p.turn) {
+		return fmt.Errorf("%w: side not to move is in check", ErrInvalidFEN)
+	}
+
+	king := p.KingSquare(p.turn)
+	checkers := 0
+	for them := p.byColor[p.turn.Other()]; them != 0; {
+		sq := them.Pop()
+		if pieceAttacks(p.board[sq].Type(), p.turn.Other(), sq, p.Occupied()).Has(king) {
+			checkers++
+		}
+	}
+	if checkers > 2 {
+		return fmt.Errorf("%w: king attacked by %d pieces", ErrInvalidFEN, checkers)
+	}
+	return nil
+}
+
+// Rangée vue du côté de la couleur (0 = première rangée)
+func relativeRank(c Color, rank int) int {
+	if c == Black {
+		return 7 - rank
+	}
+	return rank
+}
@@ -0,0 +1,71 @@
+package chess
+
+// Fog of War : chaque camp ne voit que ses pièces et les cases qu'elles peuvent atteindre.
+// L'échec n'existe pas : on peut laisser son roi en prise, et prendre le roi gagne la partie.
+type FogOfWar struct{ Standard }
+
+func (FogOfWar) Name() string { return "fogofwar" }
+
+// Tous les coups pseudo-légaux, y compris le roque à travers une case attaquée
+func (FogOfWar) LegalMoves(p *Position) []Move {
+	return p.castlingMoves(p.pieceMoves(make([]Move, 0, 64)), false)
+}
+
+func (f FogOfWar) Outcome(p *Position) (Outcome, bool) {
+	for _, c := range []Color{White, Black} {
+		if p.KingSquare(c) == NoSquare {
+			return Outcome{Termination: KingCaptured, Winner: c.Other()}, true
+		}
+	}
+	// Sans échec, l'absence de coup est toujours un pat
+	if len(f.LegalMoves(p)) == 0 {
+		return Outcome{Termination: Stalemate, Draw: true}, true
+	}
+	return Outcome{}, false
+}
+
+// Le roi peut toujours être pris, même par un roi seul
+func (FogOfWar) HasInsufficientMaterial(p *Position, c Color) bool {
+	return false
+}
+
+// Cases visibles par le camp c : ses pièces, les cases où elles peuvent aller
+// et la case devant chaque pion, même occupée
+func (p *Position) Visible(c Color) Bitboard {
+	view := *p
+	if view.turn != c {
+		// La prise en passant n'appartient qu'au camp au trait
+		view.turn = c
+		view.epSquare = NoSquare
+	}
+
+	visible := p.byColor[c]
+	for _, m := range view.castlingMoves(view.pieceMoves(make([]Move, 0, 64)), false) {
+		visible |= squareBB(m.To)
+	}
+	for pawns := p.Pieces(c, Pawn); pawns != 0; {
+		sq := pawns.Pop()
+		if c == White && sq < 56 {
+			visible |= squareBB(sq + 8)
+		} else if c == Black && sq >= 8 {
+			visible |= squareBB(sq - 8)
+		}
+	}
+	return visible
+}
+
+// Position telle que la voit le camp c : les pièces adverses hors de vue sont retirées,
+// de même que les droits de roque adverses et une case en passant invisible
+func (p *Position) FogView(c Color) Position {
+	view := *p
+	visible := p.Visible(c)
+	for hidden := p.byColor[c.Other()] &^ visible; hidden != 0; {
+		view.remove(hidden.Pop())
+	}
+	view.setCastleRook(c.Other(), kingSide, NoSquare)
+	view.setCastleRook(c.Other(), queenSide, NoSquare)
+	if view.epSquare != NoSquare && !visible.Has(view.epSquare) {
+		view.setEnPassant(NoSquare)
+	}
+	return view
+}
@@ -0,0 +1,115 @@
+package chess
+
+// Partie : position courante et historique complet pour les règles de répétition
+type Game struct {
+	positions []Position // position initiale puis position après chaque coup
+	moves     []Move
+}
+
+func NewGame(fen string) (*Game, error) {
+	start, err := ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	return &Game{positions: []Position{*start}}, nil
+}
+
+// Partie d'une variante ; une FEN vide part de la position initiale de la variante
+func NewVariantGame(v Variant, fen string) (*Game, error) {
+	if fen == "" {
+		fen = v.StartingFEN()
+	}
+	start, err := ParseVariantFEN(v, fen)
+	if err != nil {
+		return nil, err
+	}
+	return &Game{positions: []Position{*start}}, nil
+}
+
+// Règles de la partie
+func (g *Game) Variant() Variant {
+	return g.StartPosition().Variant()
+}
+
+// Position courante (ne pas modifier)
+func (g *Game) Position() *Position {
+	return &g.positions[len(g.positions)-1]
+}
+
+// Position de départ de la partie
+func (g *Game) StartPosition() *Position {
+	return &g.positions[0]
+}
+
+// Position après les ply premiers coups (0 = position de départ)
+func (g *Game) PositionAt(ply int) *Position {
+	return &g.positions[ply]
+}
+
+func (g *Game) Moves() []Move {
+	return g.moves
+}
+
+// Jouer un coup légal dans la position courante
+func (g *Game) Play(m Move) {
+	g.positions = append(g.positions, g.Position().Play(m))
+	g.moves = append(g.moves, m)
+}
+
+// Nombre d'occurrences de la position courante depuis le dernier coup irréversible
+func (g *Game) Repetitions() int {
+	current := g.Position()
+	count := 1
+	for i := len(g.positions) - 3; i >= 0 && i >= len(g.positions)-1-current.halfmove; i -= 2 {
+		if g.positions[i].hash == current.hash {
+			count++
+		}
+	}
+	return count
+}
+
+// Fin de partie imposée par les règles : mat, pat, position morte,
+// quintuple répétition ou règle des 75 coups
+func (g *Game) Outcome() (Outcome, bool) {
+	p := g.Position()
+	if outcome, over := p.Outcome(); over {
+		return outcome, true
+	}
+	if p.IsInsufficientMaterial() {
+		return Outcome{Termination: InsufficientMaterial, Draw: true}, true
+	}
+	if g.Repetitions() >= 5 {
+		return Outcome{Termination: FivefoldRepetition, Draw: true}, true
+	}
+	if p.halfmove >= 150 {
+		return Outcome{Termination: SeventyFiveMoveRule, Draw: true}, true
+	}
+	return Outcome{}, false
+}
+
+// Nulle pouvant être réclamée : triple répétition ou règle des 50 coups
+func (g *Game) ClaimableDraw() (Outcome, bool) {
+	if g.Repetitions() >= 3 {
+		return Outcome{Termination: ThreefoldRepetition, Draw: true}, true
+	}
+	if g.Position().halfmove >= 100 {
+		return Outcome{Termination: FiftyMoveRule, Draw: true}, true
+	}
+	return Outcome{}, false
+}
+
+// Ajouter une pièce à la réserve du camp c dans la position courante (Bughouse)
+func (g *Game) AddToPocket(c Color, pt PieceType) {
+	p := g.Position()
+	p.setPocket(c, pt, p.pockets[c][pt]+1)
+}
+
+// Annuler le dernier coup joué ; retourne false s'il n'y en a aucun
+func (g *Game) Undo() bool {
+	if len(g.moves) == 0 {
+		return false
+	}
+	g.positions = g.positions[:len(g.positions)-1]
+	g.moves = g.moves[:len(g.moves)-1]
+	return true
+}
@@ -0,0 +1,24 @@
+package chess
+
+// Cases centrales à atteindre avec le roi
+var hillSquares = squareBB(NewSquare(3, 3)) | squareBB(NewSquare(4, 3)) |
+	squareBB(NewSquare(3, 4)) | squareBB(NewSquare(4, 4))
+
+// King of the Hill : amener son roi au centre gagne la partie
+type KingOfTheHill struct{ Standard }
+
+func (KingOfTheHill) Name() string { return "kingofthehill" }
+
+func (KingOfTheHill) Outcome(p *Position) (Outcome, bool) {
+	for _, c := range []Color{p.turn.Other(), p.turn} {
+		if p.Pieces(c, King)&hillSquares != 0 {
+			return Outcome{Termination: KingInCenter, Winner: c}, true
+		}
+	}
+	return Outcome{}, false
+}
+
+// Le roi peut toujours marcher vers le centre
+func (KingOfTheHill) HasInsufficientMaterial(p *Position, c Color) bool {
+	return false
+}
@@ -0,0 +1,140 @@
+package chess
+
+import "fmt"
+
+type MoveFlag uint8
+
+const (
+	FlagCapture MoveFlag = 1 << iota
+	FlagEnPassant
+	FlagDoublePush
+	FlagKingCastle
+	FlagQueenCastle
+)
+
+// Coup : pour un roque, To est la case d'arrivée du roi ;
+// pour un parachutage (Crazyhouse), From vaut NoSquare et Drop la pièce posée
+type Move struct {
+	From      Square
+	To        Square
+	Promotion PieceType
+	Flags     MoveFlag
+	Drop      PieceType
+}
+
+var NullMove = Move{From: NoSquare, To: NoSquare}
+
+func (m Move) IsCapture() bool {
+	return m.Flags&FlagCapture != 0
+}
+
+func (m Move) IsDrop() bool {
+	return m.Drop != NoPieceType
+}
+
+func (m Move) IsCastle() bool {
+	return m.Flags&(FlagKingCastle|FlagQueenCastle) != 0
+}
+
+// Notation UCI standard (e2e4, e7e8q, e1g1, N@f3)
+func (m Move) UCI() string {
+	if m.IsDrop() {
+		return string(m.Drop.Letter()-'a'+'A') + "@" + m.To.String()
+	}
+	if m.From == NoSquare {
+		return "0000"
+	}
+	s := m.From.String() + m.To.String()
+	if m.Promotion != NoPieceType {
+		s += string(m.Promotion.Letter())
+	}
+	return s
+}
+
+func (m Move) String() string {
+	return m.UCI()
+}
+
+// Retrouver parmi les coups légaux celui décrit par from/to/promotion.
+// Le roque est accepté sous la forme roi -> case d'arrivée ou roi -> tour.
+func (p *Position) FindMove(from, to Square, promotion PieceType) (Move, error) {
+	for _, m := range p.LegalMoves() {
+		if m.From != from || m.Promotion != promotion {
+			continue
+		}
+		// En Chess960, le roque ne se désigne que par la case de la tour :
+		// la case d'arrivée du roi peut aussi être celle d'un simple coup de roi
+		if m.To == to && !(p.chess960 && m.IsCastle()) {
+			return m, nil
+		}
+		if m.IsCastle() && p.castleRookFor(m) == to {
+			return m, nil
+		}
+	}
+	pc := p.board[from]
+	switch {
+	case pc == NoPiece:
+		return NullMove, fmt.Errorf("%w: no piece on %s", ErrIllegalMove, from)
+	case pc.Color() != p.turn:
+		return NullMove, fmt.Errorf("%w: piece on %s belongs to %s", ErrIllegalMove, from, pc.Color())
+	case promotion == NoPieceType && pc.Type() == Pawn && (to.Rank() == 0 || to.Rank() == 7):
+		return NullMove, fmt.Errorf("%w: promotion piece required", ErrIllegalMove)
+	}
+	return NullMove, fmt.Errorf("%w: %s%s", ErrIllegalMove, from, to)
+}
+
+// Retrouver parmi les coups légaux le parachutage de la pièce pt sur to
+func (p *Position) FindDrop(pt PieceType, to Square) (Move, error) {
+	for _, m := range p.LegalMoves() {
+		if m.Drop == pt && m.To == to {
+			return m, nil
+		}
+	}
+	return NullMove, fmt.Errorf("%w: %c@%s", ErrIllegalMove, pt.Letter()-'a'+'A', to)
+}
+
+// Analyser un coup en notation UCI dans la position courante
+func (p *Position) ParseUCI(uci string) (Move, error) {
+	if len(uci) == 4 && uci[1] == '@' {
+		pt := pieceTypeFromLetter(uci[0])
+		to, err := ParseSquare(uci[2:4])
+		if pt == NoPieceType || pt == King || err != nil {
+			return NullMove, fmt.Errorf("%w: invalid uci %q", ErrIllegalMove, uci)
+		}
+		return p.FindDrop(pt, to)
+	}
+	if len(uci) < 4 || len(uci) > 5 {
+		return NullMove, fmt.Errorf("%w: invalid uci %q", ErrIllegalMove, uci)
+	}
+	from, err := ParseSquare(uci[0:2])
+	if err != nil {
+		return NullMove, fmt.Errorf("%w: %v", ErrIllegalMove, err)
+	}
+	to, err := ParseSquare(uci[2:4])
+	if err != nil {
+		return NullMove, fmt.Errorf("%w: %v", ErrIllegalMove, err)
+	}
+	promotion := NoPieceType
+	if len(uci) == 5 {
+		promotion = pieceTypeFromLetter(uci[4])
+		if promotion == NoPieceType || promotion == Pawn || promotion == King {
+			return NullMove, fmt.Errorf("%w: invalid promotion %q", ErrIllegalMove, uci[4:])
+		}
+	}
+	return p.FindMove(from, to, promotion)
+}
+
+// Notation UCI d'un coup dans la position : en Chess960, le roque s'écrit roi vers tour
+func (p *Position) UCI(m Move) string {
+	if p.chess960 && m.IsCastle() {
+		return m.From.String() + p.castleRookFor(m).String()
+	}
+	return m.UCI()
+}
+
+func (p *Position) castleRookFor(m Move) Square {
+	if m.Flags&FlagKingCastle != 0 {
+		return p.castleRooks[p.turn][kingSide]
+	}
+	return p.castleRooks[p.turn][queenSide]
+}
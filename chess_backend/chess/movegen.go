@@ -0,0 +1,139 @@
+package chess
+
+var promotionTypes = []PieceType{Queen, Rook, Bishop, Knight}
+
+// Coups légaux du camp au trait selon les règles de la variante
+func (p *Position) LegalMoves() []Move {
+	if p.variant != nil {
+		return p.variant.LegalMoves(p)
+	}
+	return p.legalMoves()
+}
+
+// Coups légaux selon les règles classiques
+func (p *Position) legalMoves() []Move {
+	pseudo := p.pseudoLegalMoves(make([]Move, 0, 64))
+	legal := pseudo[:0]
+	for _, m := range pseudo {
+		if p.isLegal(m) {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+// Un coup pseudo-légal est légal s'il ne laisse pas le roi en échec
+func (p *Position) isLegal(m Move) bool {
+	next := p.play(m)
+	king := next.KingSquare(p.turn)
+	return king == NoSquare || !next.IsAttacked(king, next.turn)
+}
+
+func (p *Position) pseudoLegalMoves(moves []Move) []Move {
+	return p.castlingMoves(p.pieceMoves(moves), true)
+}
+
+// Coups des pièces sans vérification d'échec, roques exclus
+func (p *Position) pieceMoves(moves []Move) []Move {
+	us, them := p.turn, p.turn.Other()
+	own, enemy := p.byColor[us], p.byColor[them]
+	occupied := own | enemy
+
+	moves = p.pawnMoves(moves, enemy, occupied)
+
+	for _, pt := range []PieceType{Knight, Bishop, Rook, Queen, King} {
+		for from := p.Pieces(us, pt); from != 0; {
+			sq := from.Pop()
+			for targets := pieceAttacks(pt, us, sq, occupied) &^ own; targets != 0; {
+				to := targets.Pop()
+				m := Move{From: sq, To: to}
+				if enemy.Has(to) {
+					m.Flags = FlagCapture
+				}
+				moves = append(moves, m)
+			}
+		}
+	}
+	return moves
+}
+
+func (p *Position) pawnMoves(moves []Move, enemy, occupied Bitboard) []Move {
+	us := p.turn
+	forward, startRank, lastRank := 8, 1, 7
+	if us == Black {
+		forward, startRank, lastRank = -8, 6, 0
+	}
+
+	addPawnMove := func(m Move) {
+		if m.To.Rank() == lastRank {
+			for _, pt := range promotionTypes {
+				m.Promotion = pt
+				moves = append(moves, m)
+			}
+			return
+		}
+		moves = append(moves, m)
+	}
+
+	for pawns := p.Pieces(us, Pawn); pawns != 0; {
+		from := pawns.Pop()
+
+		one := from + Square(forward)
+		if one >= 0 && one < 64 && !occupied.Has(one) {
+			addPawnMove(Move{From: from, To: one})
+			two := one + Square(forward)
+			if from.Rank() == startRank && !occupied.Has(two) {
+				moves = append(moves, Move{From: from, To: two, Flags: FlagDoublePush})
+			}
+		}
+
+		for targets := pawnAttacks[us][from] & enemy; targets != 0; {
+			addPawnMove(Move{From: from, To: targets.Pop(), Flags: FlagCapture})
+		}
+
+		if p.epSquare != NoSquare && pawnAttacks[us][from].Has(p.epSquare) {
+			moves = append(moves, Move{From: from, To: p.epSquare, Flags: FlagCapture | FlagEnPassant})
+		}
+	}
+	return moves
+}
+
+// Roques possibles ; safe impose que le roi ne parte, ne passe ni n'arrive sur une case attaquée
+func (p *Position) castlingMoves(moves []Move, safe bool) []Move {
+	us, them := p.turn, p.turn.Other()
+	king := p.KingSquare(us)
+	if king == NoSquare {
+		return moves
+	}
+
+	for side, flag := range []MoveFlag{FlagKingCastle, FlagQueenCastle} {
+		rook := p.castleRooks[us][side]
+		if rook == NoSquare {
+			continue
+		}
+		kingTo, rookTo := castleKingTarget(us, side), castleRookTarget(us, side)
+
+		// Toutes les cases traversées doivent être libres, hors roi et tour
+		path := betweenBB[king][kingTo] | squareBB(kingTo) | betweenBB[rook][rookTo] | squareBB(rookTo)
+		path &^= squareBB(king) | squareBB(rook)
+		if path&p.Occupied() != 0 {
+			continue
+		}
+
+		if !safe || p.castlingPathSafe(king, kingTo, rook, them) {
+			moves = append(moves, Move{From: king, To: kingTo, Flags: flag})
+		}
+	}
+	return moves
+}
+
+// Le roi ne doit ni partir, ni passer, ni arriver sur une case attaquée
+func (p *Position) castlingPathSafe(king, kingTo, rook Square, them Color) bool {
+	occupied := p.Occupied() &^ squareBB(king) &^ squareBB(rook)
+	for sq := betweenBB[king][kingTo] | squareBB(kingTo) | squareBB(king); sq != 0; {
+		if p.attackedBy(sq.Pop(), them, occupied) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Notation algébrique standard (SAN) d'un coup légal dans la position
+func (p *Position) SAN(m Move) string {
+	var sb strings.Builder
+
+	switch {
+	case m.IsDrop():
+		sb.WriteString(m.UCI())
+	case m.Flags&FlagKingCastle != 0:
+		sb.WriteString("O-O")
+	case m.Flags&FlagQueenCastle != 0:
+		sb.WriteString("O-O-O")
+	default:
+		pt := p.board[m.From].Type()
+		if pt == Pawn {
+			if m.IsCapture() {
+				sb.WriteByte(byte('a' + m.From.File()))
+			}
+		} else {
+			sb.WriteByte(pt.Letter() - 'a' + 'A')
+			sb.WriteString(p.disambiguation(m, pt))
+		}
+		if m.IsCapture() {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(m.To.String())
+		if m.Promotion != NoPieceType {
+			sb.WriteByte('=')
+			sb.WriteByte(m.Promotion.Letter() - 'a' + 'A')
+		}
+	}
+
+	next := p.Play(m)
+	if next.InCheck() {
+		if len(next.LegalMoves()) == 0 {
+			sb.WriteByte('#')
+		} else {
+			sb.WriteByte('+')
+		}
+	}
+	return sb.String()
+}
+
+// Colonne, rangée ou case de départ lorsque plusieurs pièces identiques peuvent jouer
+func (p *Position) disambiguation(m Move, pt PieceType) string {
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range p.LegalMoves() {
+		if other.IsDrop() || other.To != m.To || other.From == m.From || p.board[other.From].Type() != pt {
+			continue
+		}
+		ambiguous = true
+		if other.From.File() == m.From.File() {
+			sameFile = true
+		}
+		if other.From.Rank() == m.From.Rank() {
+			sameRank = true
+		}
+	}
+
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return string(rune('a' + m.From.File()))
+	case !sameRank:
+		return string(rune('1' + m.From.Rank()))
+	}
+	return m.From.String()
+}
+
+// Analyser un coup en notation SAN dans la position courante.
+// Tolère les annotations (+, #, !, ?), le roque écrit avec des zéros,
+// la promotion sans "=" et une désambiguïsation superflue.
+func (p *Position) ParseSAN(san string) (Move, error) {
+	s := strings.TrimRight(san, "+#!?")
+	s = strings.ReplaceAll(s, "0", "O")
+
+	switch s {
+	case "O-O", "O-O-O":
+		flag := FlagKingCastle
+		if s == "O-O-O" {
+			flag = FlagQueenCastle
+		}
+		for _, m := range p.LegalMoves() {
+			if m.Flags&flag != 0 {
+				return m, nil
+			}
+		}
+		return NullMove, fmt.Errorf("%w: %s", ErrIllegalMove, san)
+	}
+
+	if len(s) < 2 {
+		return NullMove, fmt.Errorf("%w: invalid san %q", ErrIllegalMove, san)
+	}
+
+	// Parachutage Crazyhouse : N@f3, P@e4 ou @e4
+	if at := strings.IndexByte(s, '@'); at >= 0 {
+		pt := Pawn
+		if at > 0 {
+			pt = pieceTypeFromLetter(s[0])
+		}
+		to, err := ParseSquare(s[at+1:])
+		if at > 1 || pt == NoPieceType || pt == King || err != nil {
+			return NullMove, fmt.Errorf("%w: invalid san %q", ErrIllegalMove, san)
+		}
+		return p.FindDrop(pt, to)
+	}
+
+	// Pièce déplacée (pion si aucune majuscule)
+	pt := Pawn
+	if c := s[0]; c >= 'A' && c <= 'Z' {
+		pt = pieceTypeFromLetter(c)
+		if pt == NoPieceType || pt == Pawn {
+			return NullMove, fmt.Errorf("%w: invalid san %q", ErrIllegalMove, san)
+		}
+		s = s[1:]
+	}
+
+	// Promotion en fin de coup
+	promotion := NoPieceType
+	if n := len(s); n > 0 && strings.ContainsRune("NBRQnbrq", rune(s[n-1])) && pt == Pawn {
+		promotion = pieceTypeFromLetter(s[n-1])
+		s = strings.TrimSuffix(s[:n-1], "=")
+	}
+
+	if len(s) < 2 {
+		return NullMove, fmt.Errorf("%w: invalid san %q", ErrIllegalMove, san)
+	}
+	to, err := ParseSquare(s[len(s)-2:])
+	if err != nil {
+		return NullMove, fmt.Errorf("%w: invalid san %q", ErrIllegalMove, san)
+	}
+
+	// Ce qui reste : désambiguïsation éventuelle et "x"
+	fromFile, fromRank := -1, -1
+	for _, c := range strings.TrimSuffix(s[:len(s)-2], "x") {
+		switch {
+		case c >= 'a' && c <= 'h':
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8':
+			fromRank = int(c - '1')
+		default:
+			return NullMove, fmt.Errorf("%w: invalid san %q", ErrIllegalMove, san)
+		}
+	}
+
+	found := NullMove
+	for _, m := range p.LegalMoves() {
+		if m.To != to || m.IsDrop() || m.IsCastle() || m.Promotion != promotion || p.board[m.From].Type() != pt {
+			continue
+		}
+		if (fromFile >= 0 && m.From.File() != fromFile) || (fromRank >= 0 && m.From.Rank() != fromRank) {
+			continue
+		}
+		if found != NullMove {
+			return NullMove, fmt.Errorf("%w: ambiguous san %q", ErrIllegalMove, san)
+		}
+		found = m
+	}
+	if found == NullMove {
+		return NullMove, fmt.Errorf("%w: %s", ErrIllegalMove, san)
+	}
+	return found, nil
+}
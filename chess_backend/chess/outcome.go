@@ -0,0 +1,114 @@
+package chess
+
+// Raison de fin de partie
+type Termination string
+
+const (
+	Checkmate                   Termination = "checkmate"
+	Stalemate                   Termination = "stalemate"
+	InsufficientMaterial        Termination = "insufficient_material"
+	ThreefoldRepetition         Termination = "threefold_repetition"
+	FivefoldRepetition          Termination = "fivefold_repetition"
+	FiftyMoveRule               Termination = "fifty_move_rule"
+	SeventyFiveMoveRule         Termination = "seventyfive_move_rule"
+	Timeout                     Termination = "timeout"
+	TimeoutInsufficientMaterial Termination = "timeout_insufficient_material"
+	DrawAgreement               Termination = "agreement"
+	Resignation                 Termination = "resignation"
+	Abandoned                   Termination = "abandoned"
+
+	// Fins propres aux variantes
+	KingInCenter Termination = "king_in_center"
+	ThreeChecks  Termination = "three_checks"
+	KingExploded Termination = "king_exploded"
+	KingRace     Termination = "king_race"
+	KingCaptured Termination = "king_captured"
+)
+
+// Résultats au format PGN
+const (
+	ResultWhiteWins = "1-0"
+	ResultBlackWins = "0-1"
+	ResultDraw      = "1/2-1/2"
+	ResultOngoing   = "*"
+)
+
+// Résultat d'une partie terminée
+type Outcome struct {
+	Termination Termination
+	Winner      Color
+	Draw        bool
+}
+
+// Résultat au format PGN (1-0, 0-1 ou 1/2-1/2)
+func (o Outcome) Result() string {
+	switch {
+	case o.Draw:
+		return ResultDraw
+	case o.Winner == White:
+		return ResultWhiteWins
+	}
+	return ResultBlackWins
+}
+
+// Détecter la fin de partie dans la position : victoire propre à la variante, mat ou pat
+func (p *Position) Outcome() (Outcome, bool) {
+	if p.variant != nil {
+		if outcome, over := p.variant.Outcome(p); over {
+			return outcome, true
+		}
+	}
+	if len(p.LegalMoves()) > 0 {
+		return Outcome{}, false
+	}
+	if p.InCheck() {
+		return Outcome{Termination: Checkmate, Winner: p.turn.Other()}, true
+	}
+	return Outcome{Termination: Stalemate, Draw: true}, true
+}
+
+var lightSquares Bitboard = 0x55aa55aa55aa55aa
+
+// Indique si le camp c ne peut en aucun cas gagner, quoi que joue l'adversaire
+func (p *Position) HasInsufficientMaterial(c Color) bool {
+	if p.variant != nil {
+		return p.variant.HasInsufficientMaterial(p, c)
+	}
+	return p.hasInsufficientMaterial(c)
+}
+
+// Matériel insuffisant pour mater selon les règles classiques
+func (p *Position) hasInsufficientMaterial(c Color) bool {
+	own := p.byColor[c]
+	if own&(p.byType[Pawn]|p.byType[Rook]|p.byType[Queen]) != 0 {
+		return false
+	}
+
+	// Roi et cavalier : mat possible seulement si l'adversaire a de quoi se bloquer
+	if own&p.byType[Knight] != 0 {
+		return own.Count() <= 2 &&
+			p.byColor[c.Other()]&^p.byType[King]&^p.byType[Queen] == 0
+	}
+
+	// Fous : insuffisant si tous les fous sont sur la même couleur, sans pion ni cavalier
+	if own&p.byType[Bishop] != 0 {
+		bishops := p.byType[Bishop]
+		sameColor := bishops&lightSquares == 0 || bishops&^lightSquares == 0
+		return sameColor && p.byType[Pawn] == 0 && p.byType[Knight] == 0
+	}
+
+	return true
+}
+
+// Position morte : aucun des deux camps ne peut mater
+func (p *Position) IsInsufficientMaterial() bool {
+	return p.HasInsufficientMaterial(White) && p.HasInsufficientMaterial(Black)
+}
+
+// Résultat lorsque le temps du camp flagged est écoulé : nulle si l'adversaire ne peut pas mater
+func (p *Position) TimeoutOutcome(flagged Color) Outcome {
+	if p.HasInsufficientMaterial(flagged.Other()) {
+		return Outcome{Termination: TimeoutInsufficientMaterial, Draw: true}
+	}
+	return Outcome{Termination: Timeout, Winner: flagged.Other()}
+}
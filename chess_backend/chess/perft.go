@@ -0,0 +1,49 @@
+package chess
+
+import "sort"
+
+// Nombre de feuilles de l'arbre des coups légaux à la profondeur depth.
+// Sert à vérifier le générateur de coups contre des valeurs de référence.
+func Perft(p *Position, depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+	moves := p.LegalMoves()
+	// Au dernier niveau, compter les coups suffit
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	var nodes uint64
+	for _, m := range moves {
+		next := p.Play(m)
+		nodes += Perft(&next, depth-1)
+	}
+	return nodes
+}
+
+// Nombre de feuilles sous un coup de la racine
+type DivideEntry struct {
+	Move  string // notation UCI de la position (roi vers tour en Chess960)
+	Nodes uint64
+}
+
+// Perft détaillé par coup de la racine, trié par notation UCI,
+// pour localiser une divergence avec un autre générateur
+func Divide(p *Position, depth int) []DivideEntry {
+	if depth <= 0 {
+		return nil
+	}
+	moves := p.LegalMoves()
+	entries := make([]DivideEntry, 0, len(moves))
+	for _, m := range moves {
+		next := p.Play(m)
+		entries = append(entries, DivideEntry{
+			Move:  p.UCI(m),
+			Nodes: Perft(&next, depth-1),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Move < entries[j].Move
+	})
+	return entries
+}
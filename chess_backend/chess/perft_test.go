@@ -0,0 +1,162 @@
+package chess
+
+import (
+	"testing"
+	"time"
+)
+
+type perftCase struct {
+	name     string
+	fen      string
+	chess960 bool
+	nodes    []uint64 // nombre de feuilles aux profondeurs 1, 2, 3...
+}
+
+// Valeurs de référence publiées sur chessprogramming.org
+var perftCases = []perftCase{
+	{name: "startpos", fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		nodes: []uint64{20, 400, 8902, 197281, 4865609}},
+	{name: "kiwipete", fen: "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		nodes: []uint64{48, 2039, 97862, 4085603}},
+	{name: "position3", fen: "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		nodes: []uint64{14, 191, 2812, 43238, 674624}},
+	{name: "position4", fen: "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		nodes: []uint64{6, 264, 9467, 422333}},
+	{name: "position5", fen: "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		nodes: []uint64{44, 1486, 62379, 2103487}},
+	{name: "position6", fen: "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		nodes: []uint64{46, 2079, 89890, 3894594}},
+
+	// Promotions
+	{name: "promotions", fen: "n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+		nodes: []uint64{24, 496, 9483, 182838}},
+	{name: "promotion-check", fen: "4k3/1P6/8/8/8/8/K7/8 w - - 0 1",
+		nodes: []uint64{9, 40, 472, 2661, 38983, 217342}},
+	{name: "underpromotion", fen: "8/P1k5/K7/8/8/8/8/8 w - - 0 1",
+		nodes: []uint64{6, 27, 273, 1329, 18135, 92683}},
+	{name: "promotion-stalemate", fen: "K1k5/8/P7/8/8/8/8/8 w - - 0 1",
+		nodes: []uint64{2, 6, 13, 63, 382, 2217}},
+
+	// Prise en passant
+	{name: "ep-discovered-check", fen: "8/8/1k6/2b5/2pP4/8/5K2/8 b - d3 0 1",
+		nodes: []uint64{15, 126, 1928, 13931, 206379, 1440467}},
+	{name: "ep-pinned", fen: "8/5k2/8/2Pp4/2B5/1K6/8/8 w - d6 0 1",
+		nodes: []uint64{15, 126, 1928, 13931, 206379, 1440467}},
+	{name: "ep-horizontal-pin", fen: "3k4/3p4/8/K1P4r/8/8/8/8 b - - 0 1",
+		nodes: []uint64{18, 92, 1670, 10138, 185429, 1134888}},
+
+	// Mat et pat
+	{name: "stalemate-checkmate", fen: "8/8/2k5/5q2/5n2/8/5K2/8 b - - 0 1",
+		nodes: []uint64{37, 183, 6559, 23527}},
+
+	// Chess960 (Shredder-FEN)
+	{name: "chess960-1", fen: "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", chess960: true,
+		nodes: []uint64{21, 528, 12189, 326672}},
+	{name: "chess960-2", fen: "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", chess960: true,
+		nodes: []uint64{21, 807, 18002, 667366}},
+	{name: "chess960-3", fen: "b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", chess960: true,
+		nodes: []uint64{20, 479, 10471, 273318}},
+}
+
+func perftPosition(t testing.TB, c perftCase) *Position {
+	var game *Game
+	var err error
+	if c.chess960 {
+		game, err = NewChess960Game(c.fen)
+	} else {
+		game, err = NewGame(c.fen)
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", c.name, err)
+	}
+	return game.Position()
+}
+
+func TestPerft(t *testing.T) {
+	for _, c := range perftCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			p := perftPosition(t, c)
+			for i, want := range c.nodes {
+				// Les profondeurs à plus d'un million de feuilles sont réservées aux tests longs
+				if testing.Short() && want > 1000000 {
+					break
+				}
+				if got := Perft(p, i+1); got != want {
+					t.Fatalf("depth %d: got %d nodes, want %d", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDivide(t *testing.T) {
+	p := perftPosition(t, perftCases[1])
+	entries := Divide(p, 2)
+	if len(entries) != 48 {
+		t.Fatalf("got %d root moves, want 48", len(entries))
+	}
+
+	var total uint64
+	counts := make(map[string]uint64)
+	for i, entry := range entries {
+		if i > 0 && entries[i-1].Move >= entry.Move {
+			t.Errorf("entries not sorted: %s before %s", entries[i-1].Move, entry.Move)
+		}
+		total += entry.Nodes
+		counts[entry.Move] = entry.Nodes
+	}
+	if total != 2039 {
+		t.Errorf("got %d nodes, want 2039", total)
+	}
+	// Roques et prise en passant apparaissent sous leur notation UCI
+	for move, want := range map[string]uint64{"e1g1": 43, "e1c1": 43, "d5e6": 46, "a2a3": 44} {
+		if counts[move] != want {
+			t.Errorf("%s: got %d nodes, want %d", move, counts[move], want)
+		}
+	}
+}
+
+func TestDivideChess960Castling(t *testing.T) {
+	p := perftPosition(t, perftCase{fen: "4k3/8/8/8/8/8/8/1R2K2R w HB - 0 1", chess960: true})
+	moves := make(map[string]bool)
+	for _, entry := range Divide(p, 1) {
+		moves[entry.Move] = true
+	}
+	// Le roque s'écrit roi vers tour : e1g1 et e1c1 sont de simples coups de roi absents ici
+	for _, move := range []string{"e1h1", "e1b1"} {
+		if !moves[move] {
+			t.Errorf("missing castling move %s", move)
+		}
+	}
+	if moves["e1g1"] || moves["e1c1"] {
+		t.Error("castling should be written king to rook")
+	}
+}
+
+func benchmarkPerft(b *testing.B, c perftCase, depth int) {
+	p := perftPosition(b, c)
+	var nodes uint64
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		nodes += Perft(p, depth)
+	}
+	b.ReportMetric(float64(nodes)/time.Since(start).Seconds(), "nodes/s")
+}
+
+func BenchmarkPerftStartpos(b *testing.B) {
+	benchmarkPerft(b, perftCases[0], 4)
+}
+
+func BenchmarkPerftKiwipete(b *testing.B) {
+	benchmarkPerft(b, perftCases[1], 3)
+}
+
+func BenchmarkPerftPromotions(b *testing.B) {
+	benchmarkPerft(b, perftCases[6], 3)
+}
+
+func BenchmarkPerftChess960(b *testing.B) {
+	benchmarkPerft(b, perftCases[14], 3)
+}
@@ -0,0 +1,501 @@
+package chess
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPGN = errors.New("invalid pgn")
+
+// Balise d'en-tête PGN
+type PGNTag struct {
+	Name  string
+	Value string
+}
+
+// Coup PGN en notation SAN, avec annotations, commentaire et variantes.
+// Chaque variante remplace ce coup et part de la position qui le précède.
+type PGNMove struct {
+	SAN        string
+	NAGs       []int
+	Comment    string
+	Variations [][]PGNMove
+}
+
+// Partie au format PGN : en-têtes ordonnés, coups et résultat
+type PGNGame struct {
+	Tags    []PGNTag
+	Comment string // commentaire précédant le premier coup
+	Moves   []PGNMove
+	Result  string
+}
+
+// Balises obligatoires, dans l'ordre du Seven Tag Roster
+var SevenTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+func (g *PGNGame) Tag(name string) string {
+	for _, tag := range g.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ""
+}
+
+// Ajouter ou remplacer une balise
+func (g *PGNGame) SetTag(name, value string) {
+	for i, tag := range g.Tags {
+		if tag.Name == name {
+			g.Tags[i].Value = value
+			return
+		}
+	}
+	g.Tags = append(g.Tags, PGNTag{Name: name, Value: value})
+}
+
+// Position de départ : balise FEN si présente, sinon position initiale
+func (g *PGNGame) StartPosition() (*Position, error) {
+	rules, err := g.Variant()
+	if err != nil {
+		return nil, err
+	}
+	fen := g.Tag("FEN")
+	if fen == "" {
+		fen = rules.StartingFEN()
+	}
+	p, err := ParseVariantFEN(rules, fen)
+	if err != nil {
+		return nil, err
+	}
+	if g.IsChess960() {
+		p.chess960 = true
+	}
+	return p, nil
+}
+
+// Règles désignées par la balise Variant
+func (g *PGNGame) Variant() (Variant, error) {
+	rules, found := VariantFromPGN(g.Tag("Variant"))
+	if !found {
+		return nil, fmt.Errorf("%w: unsupported variant %q", ErrInvalidPGN, g.Tag("Variant"))
+	}
+	return rules, nil
+}
+
+// Indique si la balise Variant désigne le Chess960
+func (g *PGNGame) IsChess960() bool {
+	switch strings.ToLower(strings.TrimSpace(g.Tag("Variant"))) {
+	case "chess960", "chess 960", "fischerandom", "fischer random", "960":
+		return true
+	}
+	return false
+}
+
+// Rejouer la ligne principale avec les règles du serveur ; les variantes sont vérifiées aussi
+func (g *PGNGame) Play() (*Game, error) {
+	start, err := g.StartPosition()
+	if err != nil {
+		return nil, err
+	}
+	game := &Game{positions: []Position{*start}}
+	if err := playLine(game, g.Moves, true); err != nil {
+		return nil, err
+	}
+	return game, nil
+}
+
+func playLine(game *Game, line []PGNMove, mainLine bool) error {
+	for _, pgnMove := range line {
+		before := *game.Position()
+		for _, variation := range pgnMove.Variations {
+			branch := &Game{positions: []Position{before}}
+			if err := playLine(branch, variation, false); err != nil {
+				return err
+			}
+		}
+
+		move, err := before.ParseSAN(pgnMove.SAN)
+		if err != nil {
+			where := "variation"
+			if mainLine {
+				where = "main line"
+			}
+			return fmt.Errorf("%s, move %d%s: %w", where, before.fullmove, moveDots(before.turn), err)
+		}
+		game.Play(move)
+	}
+	return nil
+}
+
+func moveDots(turn Color) string {
+	if turn == White {
+		return "."
+	}
+	return "..."
+}
+
+// Sérialiser au format d'export PGN (Seven Tag Roster en tête, lignes de 80 caractères au plus)
+func (g *PGNGame) String() string {
+	var sb strings.Builder
+
+	written := make(map[string]bool)
+	for _, name := range SevenTagRoster {
+		value := g.Tag(name)
+		if value == "" {
+			value = "?"
+			if name == "Result" {
+				value = g.Result
+			}
+		}
+		writeTag(&sb, name, value)
+		written[name] = true
+	}
+	for _, tag := range g.Tags {
+		if !written[tag.Name] {
+			writeTag(&sb, tag.Name, tag.Value)
+		}
+	}
+	sb.WriteByte('\n')
+
+	// Numérotation à partir de la position de départ (FEN éventuelle)
+	moveNumber, turn := 1, White
+	if start, err := g.StartPosition(); err == nil {
+		moveNumber, turn = start.fullmove, start.turn
+	}
+
+	var tokens []string
+	if g.Comment != "" {
+		tokens = append(tokens, "{"+g.Comment+"}")
+	}
+	tokens = appendLineTokens(tokens, g.Moves, moveNumber, turn)
+	tokens = append(tokens, g.Result)
+
+	lineLength := 0
+	for _, token := range tokens {
+		if lineLength > 0 && lineLength+1+len(token) > 79 {
+			sb.WriteByte('\n')
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			sb.WriteByte(' ')
+			lineLength++
+		}
+		sb.WriteString(token)
+		lineLength += len(token)
+	}
+	sb.WriteString("\n\n")
+	return sb.String()
+}
+
+func appendLineTokens(tokens []string, line []PGNMove, moveNumber int, turn Color) []string {
+	needNumber := true
+	for _, move := range line {
+		if turn == White {
+			tokens = append(tokens, fmt.Sprintf("%d.", moveNumber))
+		} else if needNumber {
+			tokens = append(tokens, fmt.Sprintf("%d...", moveNumber))
+		}
+		tokens = append(tokens, move.SAN)
+		for _, nag := range move.NAGs {
+			tokens = append(tokens, fmt.Sprintf("$%d", nag))
+		}
+		needNumber = false
+		if move.Comment != "" {
+			tokens = append(tokens, "{"+move.Comment+"}")
+			needNumber = true
+		}
+		for _, variation := range move.Variations {
+			tokens = append(tokens, "(")
+			tokens = appendLineTokens(tokens, variation, moveNumber, turn)
+			tokens = append(tokens, ")")
+			needNumber = true
+		}
+		if turn == Black {
+			moveNumber++
+		}
+		turn = turn.Other()
+	}
+	return tokens
+}
+
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	fmt.Fprintf(sb, "[%s \"%s\"]\n", name, value)
+}
+
+// Commentaire de pendule PGN ([%clk h:mm:ss])
+func ClockComment(seconds int) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	return fmt.Sprintf("[%%clk %d:%02d:%02d]", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// Extraire la commande [%clk h:mm:ss] d'un commentaire.
+// Retourne le temps en secondes (-1 si absent) et le commentaire sans la commande.
+func ParseClockComment(comment string) (int, string) {
+	start := strings.Index(comment, "[%clk")
+	if start < 0 {
+		return -1, comment
+	}
+	end := strings.Index(comment[start:], "]")
+	if end < 0 {
+		return -1, comment
+	}
+	end += start
+
+	seconds := -1
+	parts := strings.Split(strings.TrimSpace(comment[start+len("[%clk"):end]), ":")
+	if len(parts) == 3 {
+		h, errH := strconv.Atoi(parts[0])
+		m, errM := strconv.Atoi(parts[1])
+		s, errS := strconv.ParseFloat(parts[2], 64)
+		if errH == nil && errM == nil && errS == nil {
+			seconds = h*3600 + m*60 + int(s)
+		}
+	}
+
+	rest := strings.TrimSpace(comment[:start] + comment[end+1:])
+	return seconds, strings.Join(strings.Fields(rest), " ")
+}
+
+// Valeur de la balise Termination correspondant à une fin de partie
+func (t Termination) PGNTermination() string {
+	switch t {
+	case Timeout, TimeoutInsufficientMaterial:
+		return "time forfeit"
+	case Abandoned:
+		return "abandoned"
+	case "":
+		return "unterminated"
+	}
+	return "normal"
+}
+
+// Découper un fichier PGN multi-parties en textes de parties individuelles
+func SplitPGN(text string) []string {
+	var games []string
+	var current strings.Builder
+	inMovetext, braceDepth := false, 0
+
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			games = append(games, current.String())
+		}
+		current.Reset()
+		inMovetext = false
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if braceDepth == 0 && strings.HasPrefix(trimmed, "[") {
+			// Un en-tête après des coups annonce une nouvelle partie
+			if inMovetext {
+				flush()
+			}
+		} else if trimmed != "" && !strings.HasPrefix(trimmed, "%") {
+			inMovetext = true
+			braceDepth += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+			if braceDepth < 0 {
+				braceDepth = 0
+			}
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+	}
+	flush()
+	return games
+}
+
+// Analyser le texte d'une seule partie PGN (en-têtes et coups, sans validation des règles)
+func ParsePGNGame(text string) (*PGNGame, error) {
+	game := &PGNGame{Result: ResultOngoing}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	// En-têtes
+	i := 0
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "%") {
+			continue
+		}
+		if !strings.HasPrefix(line, "[") {
+			break
+		}
+		tag, err := parseTagLine(line)
+		if err != nil {
+			return nil, err
+		}
+		game.Tags = append(game.Tags, tag)
+	}
+
+	// Coups
+	var movetext strings.Builder
+	for ; i < len(lines); i++ {
+		if strings.HasPrefix(lines[i], "%") {
+			continue
+		}
+		movetext.WriteString(lines[i])
+		movetext.WriteByte('\n')
+	}
+
+	parser := &movetextParser{text: movetext.String()}
+	moves, err := parser.parseLine(0)
+	if err != nil {
+		return nil, err
+	}
+	game.Moves = moves
+	game.Comment = parser.leadingComment
+	if parser.result != "" {
+		game.Result = parser.result
+	} else if result := game.Tag("Result"); result != "" {
+		game.Result = result
+	}
+	return game, nil
+}
+
+func parseTagLine(line string) (PGNTag, error) {
+	if !strings.HasSuffix(line, "]") {
+		return PGNTag{}, fmt.Errorf("%w: unterminated tag %q", ErrInvalidPGN, line)
+	}
+	body := strings.TrimSpace(line[1 : len(line)-1])
+	space := strings.IndexAny(body, " \t")
+	if space <= 0 {
+		return PGNTag{}, fmt.Errorf("%w: malformed tag %q", ErrInvalidPGN, line)
+	}
+	name := body[:space]
+	raw := strings.TrimSpace(body[space:])
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return PGNTag{}, fmt.Errorf("%w: malformed tag value %q", ErrInvalidPGN, line)
+	}
+
+	var value strings.Builder
+	for j := 1; j < len(raw)-1; j++ {
+		if raw[j] == '\\' && j+1 < len(raw)-1 {
+			j++
+		}
+		value.WriteByte(raw[j])
+	}
+	return PGNTag{Name: name, Value: value.String()}, nil
+}
+
+// Analyseur de la section des coups : commentaires, NAG, variantes imbriquées
+type movetextParser struct {
+	text           string
+	pos            int
+	result         string
+	leadingComment string
+}
+
+var suffixNAGs = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+func (mp *movetextParser) parseLine(depth int) ([]PGNMove, error) {
+	var line []PGNMove
+	for mp.pos < len(mp.text) {
+		c := mp.text[mp.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '.':
+			mp.pos++
+
+		case c == '{':
+			end := strings.IndexByte(mp.text[mp.pos:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated comment", ErrInvalidPGN)
+			}
+			comment := strings.Join(strings.Fields(mp.text[mp.pos+1:mp.pos+end]), " ")
+			mp.pos += end + 1
+			mp.addComment(line, depth, comment)
+
+		case c == ';':
+			end := strings.IndexByte(mp.text[mp.pos:], '\n')
+			if end < 0 {
+				end = len(mp.text) - mp.pos
+			}
+			comment := strings.TrimSpace(mp.text[mp.pos+1 : mp.pos+end])
+			mp.pos += end
+			mp.addComment(line, depth, comment)
+
+		case c == '(':
+			if len(line) == 0 {
+				return nil, fmt.Errorf("%w: variation before any move", ErrInvalidPGN)
+			}
+			mp.pos++
+			variation, err := mp.parseLine(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			last := &line[len(line)-1]
+			last.Variations = append(last.Variations, variation)
+
+		case c == ')':
+			if depth == 0 {
+				return nil, fmt.Errorf("%w: unbalanced ')'", ErrInvalidPGN)
+			}
+			mp.pos++
+			return line, nil
+
+		case c == '$':
+			token := mp.readToken()
+			nag, err := strconv.Atoi(token[1:])
+			if err != nil || len(line) == 0 {
+				return nil, fmt.Errorf("%w: invalid nag %q", ErrInvalidPGN, token)
+			}
+			line[len(line)-1].NAGs = append(line[len(line)-1].NAGs, nag)
+
+		default:
+			token := mp.readToken()
+			switch token {
+			case ResultWhiteWins, ResultBlackWins, ResultDraw, ResultOngoing:
+				if depth > 0 {
+					return nil, fmt.Errorf("%w: result inside variation", ErrInvalidPGN)
+				}
+				mp.result = token
+				return line, nil
+			}
+
+			// Retirer le numéro de coup collé au coup ("12.Nf3", "12...Nf3")
+			token = strings.TrimLeft(token, "0123456789")
+			token = strings.TrimLeft(token, ".")
+			if token == "" {
+				continue
+			}
+
+			san := strings.TrimRight(token, "!?")
+			move := PGNMove{SAN: san}
+			if nag, ok := suffixNAGs[token[len(san):]]; ok {
+				move.NAGs = append(move.NAGs, nag)
+			}
+			line = append(line, move)
+		}
+	}
+
+	if depth > 0 {
+		return nil, fmt.Errorf("%w: unterminated variation", ErrInvalidPGN)
+	}
+	return line, nil
+}
+
+func (mp *movetextParser) readToken() string {
+	start := mp.pos
+	for mp.pos < len(mp.text) && !strings.ContainsRune(" \t\r\n{}();", rune(mp.text[mp.pos])) {
+		mp.pos++
+	}
+	return mp.text[start:mp.pos]
+}
+
+func (mp *movetextParser) addComment(line []PGNMove, depth int, comment string) {
+	if comment == "" {
+		return
+	}
+	if len(line) == 0 {
+		if depth == 0 {
+			mp.leadingComment = strings.TrimSpace(mp.leadingComment + " " + comment)
+		}
+		return
+	}
+	last := &line[len(line)-1]
+	last.Comment = strings.TrimSpace(last.Comment + " " + comment)
+}
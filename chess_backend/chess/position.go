@@ -0,0 +1,320 @@
+package chess
+
+// Indices des côtés de roque dans castleRooks
+const (
+	kingSide  = 0
+	queenSide = 1
+)
+
+// Position complète : placement, trait, droits de roque, prise en passant et compteurs
+type Position struct {
+	board       [64]Piece
+	byType      [7]Bitboard
+	byColor     [2]Bitboard
+	turn        Color
+	castleRooks [2][2]Square // case de la tour de roque, NoSquare si le droit est perdu
+	epSquare    Square
+	halfmove    int
+	fullmove    int
+	hash        uint64
+	chess960    bool      // roques notés roi-prend-tour en UCI
+	variant     Variant   // nil pour les règles classiques
+	checks      [2]int    // échecs restant à donner (Three-check)
+	pockets     [2][7]int // réserve de pièces par type (Crazyhouse)
+	promoted    Bitboard  // pièces issues d'une promotion, redevenant pions une fois prises
+}
+
+// Zobrist : clés fixes pour que le hash soit stable entre deux exécutions
+var (
+	zobristPieces [16][64]uint64
+	zobristCastle [64]uint64
+	zobristEP     [8]uint64
+	zobristTurn   uint64
+	zobristChecks [2][4]uint64
+	zobristPocket [2][7][17]uint64
+)
+
+func init() {
+	seed := uint64(0x9e3779b97f4a7c15)
+	next := func() uint64 {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+	for p := range zobristPieces {
+		for sq := range zobristPieces[p] {
+			zobristPieces[p][sq] = next()
+		}
+	}
+	for sq := range zobristCastle {
+		zobristCastle[sq] = next()
+	}
+	for f := range zobristEP {
+		zobristEP[f] = next()
+	}
+	zobristTurn = next()
+	for c := range zobristChecks {
+		for n := range zobristChecks[c] {
+			zobristChecks[c][n] = next()
+		}
+	}
+	for c := range zobristPocket {
+		for pt := range zobristPocket[c] {
+			for n := range zobristPocket[c][pt] {
+				zobristPocket[c][pt][n] = next()
+			}
+		}
+	}
+}
+
+func (p *Position) Turn() Color {
+	return p.turn
+}
+
+func (p *Position) EnPassant() Square {
+	return p.epSquare
+}
+
+func (p *Position) HalfmoveClock() int {
+	return p.halfmove
+}
+
+func (p *Position) FullmoveNumber() int {
+	return p.fullmove
+}
+
+// Hash Zobrist de la position (placement, trait, roques, en passant)
+func (p *Position) Hash() uint64 {
+	return p.hash
+}
+
+func (p *Position) PieceAt(sq Square) Piece {
+	return p.board[sq]
+}
+
+// Cases occupées par les pièces d'une couleur et d'un type
+func (p *Position) Pieces(c Color, pt PieceType) Bitboard {
+	return p.byType[pt] & p.byColor[c]
+}
+
+func (p *Position) Occupied() Bitboard {
+	return p.byColor[White] | p.byColor[Black]
+}
+
+func (p *Position) KingSquare(c Color) Square {
+	return p.Pieces(c, King).First()
+}
+
+func (p *Position) put(pc Piece, sq Square) {
+	p.board[sq] = pc
+	p.byType[pc.Type()] |= squareBB(sq)
+	p.byColor[pc.Color()] |= squareBB(sq)
+	p.hash ^= zobristPieces[pc][sq]
+}
+
+func (p *Position) remove(sq Square) Piece {
+	pc := p.board[sq]
+	if pc == NoPiece {
+		return NoPiece
+	}
+	p.board[sq] = NoPiece
+	p.byType[pc.Type()] &^= squareBB(sq)
+	p.byColor[pc.Color()] &^= squareBB(sq)
+	p.hash ^= zobristPieces[pc][sq]
+	return pc
+}
+
+func (p *Position) setCastleRook(c Color, side int, sq Square) {
+	if old := p.castleRooks[c][side]; old != NoSquare {
+		p.hash ^= zobristCastle[old]
+	}
+	p.castleRooks[c][side] = sq
+	if sq != NoSquare {
+		p.hash ^= zobristCastle[sq]
+	}
+}
+
+func (p *Position) setChecks(c Color, n int) {
+	if n < 0 {
+		n = 0
+	}
+	p.hash ^= zobristChecks[c][p.checks[c]&3]
+	p.checks[c] = n
+	p.hash ^= zobristChecks[c][n&3]
+}
+
+func (p *Position) setPocket(c Color, pt PieceType, n int) {
+	if n < 0 {
+		n = 0
+	}
+	p.hash ^= zobristPocket[c][pt][p.pockets[c][pt]%17]
+	p.pockets[c][pt] = n
+	p.hash ^= zobristPocket[c][pt][n%17]
+}
+
+func (p *Position) setEnPassant(sq Square) {
+	if p.epSquare != NoSquare {
+		p.hash ^= zobristEP[p.epSquare.File()]
+	}
+	p.epSquare = sq
+	if sq != NoSquare {
+		p.hash ^= zobristEP[sq.File()]
+	}
+}
+
+// Indique si la case est attaquée par le camp by, avec une occupation donnée
+func (p *Position) attackedBy(sq Square, by Color, occupied Bitboard) bool {
+	them := p.byColor[by]
+	if pawnAttacks[by.Other()][sq]&p.byType[Pawn]&them != 0 {
+		return true
+	}
+	if knightAttacks[sq]&p.byType[Knight]&them != 0 {
+		return true
+	}
+	if kingAttacks[sq]&p.byType[King]&them != 0 {
+		return true
+	}
+	if bishopAttacks(sq, occupied)&(p.byType[Bishop]|p.byType[Queen])&them != 0 {
+		return true
+	}
+	return rookAttacks(sq, occupied)&(p.byType[Rook]|p.byType[Queen])&them != 0
+}
+
+// Indique si la case est attaquée par le camp by
+func (p *Position) IsAttacked(sq Square, by Color) bool {
+	return p.attackedBy(sq, by, p.Occupied())
+}
+
+// Indique si le camp au trait est en échec
+func (p *Position) InCheck() bool {
+	king := p.KingSquare(p.turn)
+	return king != NoSquare && p.IsAttacked(king, p.turn.Other())
+}
+
+// Appliquer un coup légal selon les règles de la variante et retourner la nouvelle position
+func (p *Position) Play(m Move) Position {
+	if p.variant != nil {
+		return p.variant.Play(p, m)
+	}
+	return p.play(m)
+}
+
+// Règles de la position (Standard si aucune variante)
+func (p *Position) Variant() Variant {
+	if p.variant == nil {
+		return Standard{}
+	}
+	return p.variant
+}
+
+// Nombre de pièces pt dans la réserve du camp c (Crazyhouse)
+func (p *Position) Pocket(c Color, pt PieceType) int {
+	return p.pockets[c][pt]
+}
+
+// Échecs restant à donner par le camp c (Three-check)
+func (p *Position) RemainingChecks(c Color) int {
+	return p.checks[c]
+}
+
+// Appliquer un coup supposé pseudo-légal selon les règles classiques
+func (p *Position) play(m Move) Position {
+	next := *p
+	next.makeMove(m)
+	return next
+}
+
+func (p *Position) makeMove(m Move) {
+	us, them := p.turn, p.turn.Other()
+	moving := p.board[m.From]
+
+	p.setEnPassant(NoSquare)
+	p.halfmove++
+
+	switch {
+	case m.IsCastle():
+		side := kingSide
+		if m.Flags&FlagQueenCastle != 0 {
+			side = queenSide
+		}
+		rookFrom := p.castleRooks[us][side]
+		rookTo := castleRookTarget(us, side)
+		p.remove(m.From)
+		rook := p.remove(rookFrom)
+		p.put(moving, m.To)
+		p.put(rook, rookTo)
+
+	default:
+		if m.Flags&FlagEnPassant != 0 {
+			if us == White {
+				p.remove(m.To - 8)
+			} else {
+				p.remove(m.To + 8)
+			}
+		} else if captured := p.remove(m.To); captured != NoPiece {
+			p.clearCastleRook(them, m.To)
+		}
+		p.remove(m.From)
+		if m.Promotion != NoPieceType {
+			p.put(NewPiece(us, m.Promotion), m.To)
+		} else {
+			p.put(moving, m.To)
+		}
+		if m.Flags&FlagCapture != 0 || moving.Type() == Pawn {
+			p.halfmove = 0
+		}
+		if m.Flags&FlagDoublePush != 0 {
+			// N'enregistrer la case en passant que si une prise est possible
+			ep := (m.From + m.To) / 2
+			if pawnAttacks[us][ep]&p.Pieces(them, Pawn) != 0 {
+				p.setEnPassant(ep)
+			}
+		}
+	}
+
+	if moving.Type() == King {
+		p.setCastleRook(us, kingSide, NoSquare)
+		p.setCastleRook(us, queenSide, NoSquare)
+	} else {
+		p.clearCastleRook(us, m.From)
+	}
+
+	if us == Black {
+		p.fullmove++
+	}
+	p.turn = them
+	p.hash ^= zobristTurn
+}
+
+func (p *Position) clearCastleRook(c Color, sq Square) {
+	for side := range p.castleRooks[c] {
+		if p.castleRooks[c][side] == sq {
+			p.setCastleRook(c, side, NoSquare)
+		}
+	}
+}
+
+// Cases d'arrivée du roi et de la tour après le roque
+func castleKingTarget(c Color, side int) Square {
+	rank := 0
+	if c == Black {
+		rank = 7
+	}
+	if side == kingSide {
+		return NewSquare(6, rank)
+	}
+	return NewSquare(2, rank)
+}
+
+func castleRookTarget(c Color, side int) Square {
+	rank := 0
+	if c == Black {
+		rank = 7
+	}
+	if side == kingSide {
+		return NewSquare(5, rank)
+	}
+	return NewSquare(3, rank)
+}
@@ -0,0 +1,48 @@
+package chess
+
+// Racing Kings : le premier roi sur la huitième rangée gagne ; donner échec est interdit
+type RacingKings struct{ Standard }
+
+func (RacingKings) Name() string        { return "racingkings" }
+func (RacingKings) StartingFEN() string { return "8/8/8/8/8/8/krbnNBRK/qrbnNBRQ w - - 0 1" }
+
+func (RacingKings) LegalMoves(p *Position) []Move {
+	moves := p.legalMoves()
+	legal := moves[:0]
+	for _, m := range moves {
+		next := p.play(m)
+		if !next.InCheck() {
+			legal = append(legal, m)
+		}
+	}
+	return legal
+}
+
+func (r RacingKings) Outcome(p *Position) (Outcome, bool) {
+	whiteHome := p.Pieces(White, King)&rank8 != 0
+	blackHome := p.Pieces(Black, King)&rank8 != 0
+
+	switch {
+	case whiteHome && blackHome:
+		return Outcome{Termination: KingRace, Draw: true}, true
+	case blackHome:
+		return Outcome{Termination: KingRace, Winner: Black}, true
+	case whiteHome:
+		// Les noirs jouent en second : ils ont un coup pour égaliser
+		if p.turn == Black {
+			king := p.KingSquare(Black)
+			for _, m := range r.LegalMoves(p) {
+				if m.From == king && rank8.Has(m.To) {
+					return Outcome{}, false
+				}
+			}
+		}
+		return Outcome{Termination: KingRace, Winner: White}, true
+	}
+	return Outcome{}, false
+}
+
+// Les rois peuvent toujours courir
+func (RacingKings) HasInsufficientMaterial(p *Position, c Color) bool {
+	return false
+}
@@ -0,0 +1,81 @@
+package chess
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Three-check : donner trois échecs gagne la partie
+type ThreeCheck struct{ Standard }
+
+func (ThreeCheck) Name() string { return "threecheck" }
+func (ThreeCheck) StartingFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1"
+}
+
+func (ThreeCheck) Play(p *Position, m Move) Position {
+	next := p.play(m)
+	if next.InCheck() {
+		next.setChecks(p.turn, next.checks[p.turn]-1)
+	}
+	return next
+}
+
+func (ThreeCheck) Outcome(p *Position) (Outcome, bool) {
+	for _, c := range []Color{p.turn.Other(), p.turn} {
+		if p.checks[c] <= 0 {
+			return Outcome{Termination: ThreeChecks, Winner: c}, true
+		}
+	}
+	return Outcome{}, false
+}
+
+// Un roi seul ne peut pas donner échec
+func (ThreeCheck) HasInsufficientMaterial(p *Position, c Color) bool {
+	return p.byColor[c] == p.Pieces(c, King)
+}
+
+// Échecs restants après la case en passant ("3+3") ou échecs donnés en fin de FEN ("+0+0")
+func (ThreeCheck) ParseFENFields(p *Position, fields []string) ([]string, error) {
+	p.setChecks(White, 3)
+	p.setChecks(Black, 3)
+
+	rest := make([]string, 0, len(fields))
+	for i, field := range fields {
+		switch {
+		case i == 4 && strings.Count(field, "+") == 1 && !strings.HasPrefix(field, "+"):
+			white, black, err := parseCheckCounts(field)
+			if err != nil {
+				return nil, err
+			}
+			p.setChecks(White, white)
+			p.setChecks(Black, black)
+		case i >= 4 && strings.HasPrefix(field, "+") && strings.Count(field, "+") == 2:
+			white, black, err := parseCheckCounts(field[1:])
+			if err != nil {
+				return nil, err
+			}
+			p.setChecks(White, 3-white)
+			p.setChecks(Black, 3-black)
+		default:
+			rest = append(rest, field)
+		}
+	}
+	return rest, nil
+}
+
+func parseCheckCounts(field string) (int, int, error) {
+	parts := strings.Split(field, "+")
+	white, errWhite := strconv.Atoi(parts[0])
+	black, errBlack := strconv.Atoi(parts[1])
+	if errWhite != nil || errBlack != nil || white < 0 || white > 3 || black < 0 || black > 3 {
+		return 0, 0, fmt.Errorf("%w: invalid check counts %q", ErrInvalidFEN, field)
+	}
+	return white, black, nil
+}
+
+func (ThreeCheck) FormatFENFields(p *Position, fields []string) []string {
+	checks := fmt.Sprintf("%d+%d", p.checks[White], p.checks[Black])
+	return append(fields[:4:4], append([]string{checks}, fields[4:]...)...)
+}
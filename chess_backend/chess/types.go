@@ -0,0 +1,176 @@
+package chess
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Couleur d'un camp
+type Color uint8
+
+const (
+	White Color = iota
+	Black
+)
+
+func (c Color) Other() Color {
+	return c ^ 1
+}
+
+func (c Color) String() string {
+	if c == White {
+		return "white"
+	}
+	return "black"
+}
+
+// Type de pièce, indépendant de la couleur
+type PieceType uint8
+
+const (
+	NoPieceType PieceType = iota
+	Pawn
+	Knight
+	Bishop
+	Rook
+	Queen
+	King
+)
+
+const pieceLetters = " pnbrqk"
+
+// Lettre minuscule de la pièce (p, n, b, r, q, k)
+func (pt PieceType) Letter() byte {
+	return pieceLetters[pt]
+}
+
+func pieceTypeFromLetter(c byte) PieceType {
+	switch c {
+	case 'p', 'P':
+		return Pawn
+	case 'n', 'N':
+		return Knight
+	case 'b', 'B':
+		return Bishop
+	case 'r', 'R':
+		return Rook
+	case 'q', 'Q':
+		return Queen
+	case 'k', 'K':
+		return King
+	}
+	return NoPieceType
+}
+
+// Pièce colorée : bit 3 pour la couleur, bits 0-2 pour le type
+type Piece uint8
+
+const NoPiece Piece = 0
+
+func NewPiece(c Color, pt PieceType) Piece {
+	return Piece(uint8(c)<<3 | uint8(pt))
+}
+
+func (p Piece) Color() Color {
+	return Color(p >> 3)
+}
+
+func (p Piece) Type() PieceType {
+	return PieceType(p & 7)
+}
+
+// Lettre FEN de la pièce (majuscule pour les blancs)
+func (p Piece) Letter() byte {
+	l := p.Type().Letter()
+	if p.Color() == White {
+		return l - 'a' + 'A'
+	}
+	return l
+}
+
+func pieceFromLetter(c byte) Piece {
+	pt := pieceTypeFromLetter(c)
+	if pt == NoPieceType {
+		return NoPiece
+	}
+	if c >= 'A' && c <= 'Z' {
+		return NewPiece(White, pt)
+	}
+	return NewPiece(Black, pt)
+}
+
+// Case de l'échiquier : 0 = a1, 7 = h1, 63 = h8
+type Square int8
+
+const NoSquare Square = -1
+
+func NewSquare(file, rank int) Square {
+	return Square(rank*8 + file)
+}
+
+func (s Square) File() int {
+	return int(s) & 7
+}
+
+func (s Square) Rank() int {
+	return int(s) >> 3
+}
+
+func (s Square) String() string {
+	if s == NoSquare {
+		return "-"
+	}
+	return string([]byte{byte('a' + s.File()), byte('1' + s.Rank())})
+}
+
+// Convertir une notation algébrique ("e4") en case
+func ParseSquare(name string) (Square, error) {
+	if len(name) != 2 || name[0] < 'a' || name[0] > 'h' || name[1] < '1' || name[1] > '8' {
+		return NoSquare, fmt.Errorf("invalid square %q", name)
+	}
+	return NewSquare(int(name[0]-'a'), int(name[1]-'1')), nil
+}
+
+// Ensemble de cases représenté sur 64 bits
+type Bitboard uint64
+
+func (b Bitboard) Has(s Square) bool {
+	return b&(1<<uint(s)) != 0
+}
+
+func (b Bitboard) Count() int {
+	return bits.OnesCount64(uint64(b))
+}
+
+// Première case de l'ensemble (NoSquare si vide)
+func (b Bitboard) First() Square {
+	if b == 0 {
+		return NoSquare
+	}
+	return Square(bits.TrailingZeros64(uint64(b)))
+}
+
+func (b Bitboard) last() Square {
+	if b == 0 {
+		return NoSquare
+	}
+	return Square(63 - bits.LeadingZeros64(uint64(b)))
+}
+
+// Retirer et retourner la première case de l'ensemble
+func (b *Bitboard) Pop() Square {
+	s := Square(bits.TrailingZeros64(uint64(*b)))
+	*b &= *b - 1
+	return s
+}
+
+func squareBB(s Square) Bitboard {
+	return 1 << uint(s)
+}
+
+const (
+	fileA Bitboard = 0x0101010101010101
+	fileH Bitboard = fileA << 7
+	rank1 Bitboard = 0xff
+	rank8 Bitboard = rank1 << 56
+)
@@ -0,0 +1,99 @@
+package chess
+
+import (
+	"strings"
+)
+
+// Règles d'une variante : génération des coups, conditions de victoire
+// supplémentaires et champs FEN propres à la variante
+type Variant interface {
+	// Identifiant utilisé dans les invitations et l'archive
+	Name() string
+	StartingFEN() string
+
+	// Coups légaux du camp au trait
+	LegalMoves(p *Position) []Move
+	// Jouer un coup légal, avec ses effets propres à la variante
+	Play(p *Position, m Move) Position
+
+	// Fin de partie propre à la variante, évaluée avant le mat et le pat
+	Outcome(p *Position) (Outcome, bool)
+	// Indique si le camp c ne peut plus gagner
+	HasInsufficientMaterial(p *Position, c Color) bool
+
+	// Lire et retirer les champs FEN de la variante avant l'analyse classique
+	ParseFENFields(p *Position, fields []string) ([]string, error)
+	// Ajouter les champs de la variante aux six champs FEN classiques
+	FormatFENFields(p *Position, fields []string) []string
+}
+
+// Règles classiques ; les variantes l'embarquent et ne redéfinissent que ce qui change
+type Standard struct{}
+
+func (Standard) Name() string        { return "standard" }
+func (Standard) StartingFEN() string { return StartingFEN }
+
+func (Standard) LegalMoves(p *Position) []Move {
+	return p.legalMoves()
+}
+
+func (Standard) Play(p *Position, m Move) Position {
+	return p.play(m)
+}
+
+func (Standard) Outcome(p *Position) (Outcome, bool) {
+	return Outcome{}, false
+}
+
+func (Standard) HasInsufficientMaterial(p *Position, c Color) bool {
+	return p.hasInsufficientMaterial(c)
+}
+
+func (Standard) ParseFENFields(p *Position, fields []string) ([]string, error) {
+	return fields, nil
+}
+
+func (Standard) FormatFENFields(p *Position, fields []string) []string {
+	return fields
+}
+
+// Variantes disponibles, par identifiant
+var variants = map[string]Variant{}
+
+func registerVariant(v Variant) {
+	variants[v.Name()] = v
+}
+
+func init() {
+	registerVariant(Standard{})
+	registerVariant(KingOfTheHill{})
+	registerVariant(ThreeCheck{})
+	registerVariant(Atomic{})
+	registerVariant(RacingKings{})
+	registerVariant(Crazyhouse{})
+	registerVariant(Bughouse{})
+	registerVariant(FogOfWar{})
+}
+
+// Retrouver une variante par son identifiant
+func VariantByName(name string) (Variant, bool) {
+	v, found := variants[name]
+	return v, found
+}
+
+// Retrouver une variante à partir de la balise PGN Variant ("King of the Hill", "Three-check"...)
+func VariantFromPGN(name string) (Variant, bool) {
+	key := strings.ToLower(name)
+	key = strings.NewReplacer(" ", "", "-", "", "_", "").Replace(key)
+	switch key {
+	case "", "standard", "chess960", "chess", "fischerandom", "fischerrandom", "960":
+		return Standard{}, true
+	case "koth":
+		key = "kingofthehill"
+	case "3check":
+		key = "threecheck"
+	case "fog", "dark", "darkchess":
+		key = "fogofwar"
+	}
+	return VariantByName(key)
+}
@@ -0,0 +1,67 @@
+// Commande perft : compte les positions atteignables à une profondeur donnée
+// pour vérifier le générateur de coups.
+//
+//	go run ./cmd/perft -depth 5
+//	go run ./cmd/perft -fen "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1" -depth 4 -divide
+//	go run ./cmd/perft -chess960 -fen "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9" -depth 4
+package main
+
+import (
+	"chess_backend/chess"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+func main() {
+	fen := flag.String("fen", "", "starting position (defaults to the variant's initial position)")
+	depth := flag.Int("depth", 4, "search depth in plies")
+	variantName := flag.String("variant", "standard", "variant rules")
+	chess960 := flag.Bool("chess960", false, "Chess960 castling rules")
+	divide := flag.Bool("divide", false, "print the node count of each root move")
+	flag.Parse()
+
+	game, err := newGame(*variantName, *fen, *chess960)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	position := game.Position()
+
+	start := time.Now()
+	var nodes uint64
+	if *divide {
+		for _, entry := range chess.Divide(position, *depth) {
+			fmt.Printf("%s: %d\n", entry.Move, entry.Nodes)
+			nodes += entry.Nodes
+		}
+		fmt.Println()
+	} else {
+		nodes = chess.Perft(position, *depth)
+	}
+	elapsed := time.Since(start)
+
+	fmt.Printf("Nodes: %d\n", nodes)
+	fmt.Printf("Time: %v\n", elapsed.Round(time.Millisecond))
+	if elapsed > 0 {
+		fmt.Printf("NPS: %.0f\n", float64(nodes)/elapsed.Seconds())
+	}
+}
+
+func newGame(variantName, fen string, chess960 bool) (*chess.Game, error) {
+	variant, found := chess.VariantByName(variantName)
+	if !found {
+		return nil, fmt.Errorf("unknown variant %q", variantName)
+	}
+	if chess960 {
+		if fen == "" {
+			return nil, fmt.Errorf("a FEN is required for Chess960")
+		}
+		if variant.Name() != (chess.Standard{}).Name() {
+			return nil, fmt.Errorf("Chess960 is only supported with standard rules")
+		}
+		return chess.NewChess960Game(fen)
+	}
+	return chess.NewVariantGame(variant, fen)
+}
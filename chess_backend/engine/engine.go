@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"chess_backend/chess"
+	"math/rand"
+	"time"
+)
+
+// Bornes des scores, en centipions du point de vue du camp au trait
+const (
+	Infinity      = 32000
+	MateScore     = 31000
+	MateThreshold = MateScore - 1000
+	MaxDepth      = 64
+)
+
+// Limites d'une recherche ; une valeur nulle signifie sans limite
+type Limits struct {
+	Depth    int
+	MoveTime time.Duration
+	Nodes    uint64
+	Noise    int // amplitude du bruit ajouté à l'évaluation, en centipions
+	MultiPV  int // nombre de meilleures variantes à calculer (1 par défaut)
+
+	// Appelée après chaque profondeur terminée
+	OnDepth func(Result)
+}
+
+// Variante calculée : coups à partir de la position et score du camp au trait
+type Line struct {
+	Moves []chess.Move
+	Score int
+}
+
+// Résultat d'une recherche
+type Result struct {
+	Move  chess.Move
+	Score int // du point de vue du camp au trait
+	Depth int
+	Nodes uint64
+	PV    []chess.Move
+	Lines []Line // meilleures variantes, de la meilleure à la moins bonne
+	Time  time.Duration
+}
+
+// Indique si le score annonce un mat, pour l'un ou l'autre camp
+func IsMateScore(score int) bool {
+	return score > MateThreshold || score < -MateThreshold
+}
+
+// Moteur intégré : alpha-bêta à approfondissement itératif, quiescence et
+// table de transposition. Un moteur ne doit servir qu'à une recherche à la fois.
+type Engine struct {
+	tt        *transpositionTable
+	killers   [MaxDepth][2]chess.Move
+	path      []uint64     // positions précédentes, pour détecter les répétitions
+	excluded  []chess.Move // coups racine déjà retenus dans une variante précédente
+	limits    Limits
+	deadline  time.Time
+	nodes     uint64
+	stopped   bool
+	noiseSeed uint64
+	rng       *rand.Rand
+}
+
+func New() *Engine {
+	return &Engine{
+		tt:  newTranspositionTable(18),
+		rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Oublier les positions analysées lors d'une partie précédente
+func (e *Engine) NewGame() {
+	e.tt.clear()
+}
+
+// Chercher le meilleur coup de la position. history contient les hashes des positions
+// déjà jouées dans la partie, de la plus ancienne à la précédente.
+func (e *Engine) Search(p *chess.Position, history []uint64, limits Limits) Result {
+	start := time.Now()
+	e.limits = limits
+	e.nodes = 0
+	e.stopped = false
+	e.deadline = time.Time{}
+	if limits.MoveTime > 0 {
+		e.deadline = start.Add(limits.MoveTime)
+	}
+	e.noiseSeed = e.rng.Uint64()
+	e.killers = [MaxDepth][2]chess.Move{}
+
+	// Seules les positions depuis le dernier coup irréversible peuvent se répéter
+	keep := p.HalfmoveClock()
+	if keep > len(history) {
+		keep = len(history)
+	}
+	e.path = append(e.path[:0], history[len(history)-keep:]...)
+
+	result := Result{Move: chess.NullMove}
+	moves := p.LegalMoves()
+	if len(moves) == 0 {
+		return result
+	}
+	result.Move = moves[0]
+
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth > MaxDepth {
+		maxDepth = MaxDepth
+	}
+	multiPV := limits.MultiPV
+	if multiPV < 1 {
+		multiPV = 1
+	}
+	if multiPV > len(moves) {
+		multiPV = len(moves)
+	}
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		lines := e.searchLines(p, depth, multiPV)
+		// Une itération interrompue n'est pas fiable, sauf s'il n'y en a pas d'autre
+		if len(lines) == 0 || (e.stopped && depth > 1) {
+			break
+		}
+		result.Move = lines[0].Moves[0]
+		result.Score = lines[0].Score
+		result.PV = lines[0].Moves
+		result.Lines = lines
+		result.Depth = depth
+		if e.stopped {
+			break
+		}
+		if limits.OnDepth != nil {
+			result.Nodes = e.nodes
+			result.Time = time.Since(start)
+			limits.OnDepth(result)
+		}
+		// Mat trouvé dans l'horizon : inutile de chercher plus loin
+		if multiPV == 1 && IsMateScore(result.Score) && MateScore-abs(result.Score) <= depth {
+			break
+		}
+	}
+
+	result.Nodes = e.nodes
+	result.Time = time.Since(start)
+	return result
+}
+
+// Chercher les multiPV meilleures variantes à la profondeur donnée :
+// chaque recherche exclut les premiers coups des variantes déjà trouvées
+func (e *Engine) searchLines(p *chess.Position, depth, multiPV int) []Line {
+	var lines []Line
+	e.excluded = e.excluded[:0]
+	for len(lines) < multiPV {
+		score := e.negamax(p, depth, -Infinity, Infinity, 0)
+		if e.stopped && (depth > 1 || len(lines) > 0) {
+			break
+		}
+		pv := e.principalVariation(p, depth)
+		if len(pv) == 0 || e.isExcluded(pv[0]) {
+			break
+		}
+		lines = append(lines, Line{Moves: pv, Score: score})
+		e.excluded = append(e.excluded, pv[0])
+		if e.stopped {
+			break
+		}
+	}
+	e.excluded = e.excluded[:0]
+	return lines
+}
+
+func (e *Engine) isExcluded(m chess.Move) bool {
+	for _, excluded := range e.excluded {
+		if excluded == m {
+			return true
+		}
+	}
+	return false
+}
+
+// Ligne principale reconstruite à partir de la table de transposition
+func (e *Engine) principalVariation(p *chess.Position, depth int) []chess.Move {
+	var pv []chess.Move
+	seen := make(map[uint64]bool)
+	position := *p
+	for len(pv) < depth {
+		entry, found := e.tt.probe(position.Hash())
+		if !found || seen[position.Hash()] || !isLegalMove(&position, entry.move) {
+			break
+		}
+		seen[position.Hash()] = true
+		pv = append(pv, entry.move)
+		position = position.Play(entry.move)
+	}
+	return pv
+}
+
+func isLegalMove(p *chess.Position, m chess.Move) bool {
+	for _, legal := range p.LegalMoves() {
+		if legal == m {
+			return true
+		}
+	}
+	return false
+}
+
+// Vérifier régulièrement le temps et le nombre de nœuds
+func (e *Engine) shouldStop() bool {
+	if e.stopped {
+		return true
+	}
+	if e.limits.Nodes > 0 && e.nodes >= e.limits.Nodes {
+		e.stopped = true
+	} else if e.nodes&1023 == 0 && !e.deadline.IsZero() && time.Now().After(e.deadline) {
+		e.stopped = true
+	}
+	return e.stopped
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
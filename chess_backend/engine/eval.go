@@ -0,0 +1,134 @@
+package engine
+
+import "chess_backend/chess"
+
+// Valeur des pièces en centipions
+var pieceValues = [7]int{0, 100, 320, 330, 500, 900, 0}
+
+// Tables pièce-case vues par les blancs, de a8 (index 0) à h1 (index 63)
+var pieceSquareTables = [7][64]int{
+	chess.Pawn: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	},
+	chess.Knight: {
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	},
+	chess.Bishop: {
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	},
+	chess.Rook: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	},
+	chess.Queen: {
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	},
+	chess.King: {
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	},
+}
+
+// Roi en finale : il doit rejoindre le centre
+var kingEndgameTable = [64]int{
+	-50, -40, -30, -20, -20, -30, -40, -50,
+	-30, -20, -10, 0, 0, -10, -20, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -30, 0, 0, 0, 0, -30, -30,
+	-50, -30, -30, -30, -30, -30, -30, -50,
+}
+
+// Bonus du camp au trait
+const tempo = 10
+
+// Évaluation statique du point de vue du camp au trait : matériel (réserve comprise)
+// et placement des pièces, avec une table de roi propre à la finale
+func Evaluate(p *chess.Position) int {
+	var scores [2]int
+	endgame := isEndgame(p)
+
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		for pt := chess.Pawn; pt <= chess.King; pt++ {
+			table := &pieceSquareTables[pt]
+			if pt == chess.King && endgame {
+				table = &kingEndgameTable
+			}
+			for pieces := p.Pieces(c, pt); pieces != 0; {
+				sq := pieces.Pop()
+				scores[c] += pieceValues[pt] + table[tableIndex(c, sq)]
+			}
+			scores[c] += p.Pocket(c, pt) * pieceValues[pt]
+		}
+	}
+
+	score := scores[chess.White] - scores[chess.Black]
+	if p.Turn() == chess.Black {
+		score = -score
+	}
+	return score + tempo
+}
+
+// Index dans les tables, écrites depuis a8 du point de vue des blancs
+func tableIndex(c chess.Color, sq chess.Square) int {
+	if c == chess.White {
+		return int(sq) ^ 56
+	}
+	return int(sq)
+}
+
+// Finale : plus de dames, ou au plus une pièce mineure à côté de chaque dame
+func isEndgame(p *chess.Position) bool {
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		if p.Pieces(c, chess.Queen) == 0 {
+			continue
+		}
+		minors := p.Pieces(c, chess.Knight) | p.Pieces(c, chess.Bishop)
+		if p.Pieces(c, chess.Rook) != 0 || minors.Count() > 1 {
+			return false
+		}
+	}
+	return true
+}
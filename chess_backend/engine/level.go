@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// Niveau de jeu : profondeur maximale, temps de réflexion et bruit d'évaluation
+type Level struct {
+	Depth    int
+	MoveTime time.Duration
+	Noise    int
+}
+
+const (
+	MinLevel     = 1
+	MaxLevel     = 8
+	DefaultLevel = 3
+)
+
+// Du débutant (niveau 1) au plus fort (niveau 8)
+var levels = [MaxLevel + 1]Level{
+	1: {Depth: 1, MoveTime: 100 * time.Millisecond, Noise: 300},
+	2: {Depth: 2, MoveTime: 200 * time.Millisecond, Noise: 150},
+	3: {Depth: 3, MoveTime: 300 * time.Millisecond, Noise: 80},
+	4: {Depth: 4, MoveTime: 500 * time.Millisecond, Noise: 40},
+	5: {Depth: 5, MoveTime: 1 * time.Second, Noise: 20},
+	6: {Depth: 6, MoveTime: 2 * time.Second, Noise: 10},
+	7: {Depth: 8, MoveTime: 3 * time.Second},
+	8: {Depth: MaxDepth, MoveTime: 5 * time.Second},
+}
+
+func LevelFor(n int) (Level, error) {
+	if n < MinLevel || n > MaxLevel {
+		return Level{}, fmt.Errorf("level must be between %d and %d", MinLevel, MaxLevel)
+	}
+	return levels[n], nil
+}
+
+// Limites de recherche du niveau, en ne dépassant pas le temps accordé par la pendule
+func (l Level) Limits(budget time.Duration) Limits {
+	moveTime := l.MoveTime
+	if budget > 0 && budget < moveTime {
+		moveTime = budget
+	}
+	return Limits{Depth: l.Depth, MoveTime: moveTime, Noise: l.Noise}
+}
@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"chess_backend/chess"
+	"sort"
+)
+
+func (e *Engine) negamax(p *chess.Position, depth, alpha, beta, ply int) int {
+	if e.shouldStop() {
+		return 0
+	}
+	e.nodes++
+
+	if ply > 0 && e.isDraw(p) {
+		return 0
+	}
+	if outcome, over := p.Variant().Outcome(p); over {
+		return outcomeScore(outcome, p.Turn(), ply)
+	}
+
+	inCheck := p.InCheck()
+	if inCheck {
+		depth++
+	}
+	if depth <= 0 {
+		return e.quiescence(p, alpha, beta, ply)
+	}
+	if ply >= MaxDepth-1 {
+		return e.evaluate(p)
+	}
+
+	key := p.Hash()
+	ttMove := chess.NullMove
+	if entry, found := e.tt.probe(key); found {
+		ttMove = entry.move
+		if ply > 0 && int(entry.depth) >= depth {
+			score := scoreFromTT(int(entry.score), ply)
+			switch {
+			case entry.bound == boundExact,
+				entry.bound == boundLower && score >= beta,
+				entry.bound == boundUpper && score <= alpha:
+				return score
+			}
+		}
+	}
+
+	moves := p.LegalMoves()
+	if len(moves) == 0 {
+		if inCheck {
+			return -MateScore + ply
+		}
+		return 0
+	}
+	e.orderMoves(p, moves, ttMove, ply)
+
+	e.path = append(e.path, key)
+	defer func() { e.path = e.path[:len(e.path)-1] }()
+
+	originalAlpha := alpha
+	best, bestMove := -Infinity, chess.NullMove
+	for _, m := range moves {
+		if ply == 0 && e.isExcluded(m) {
+			continue
+		}
+		next := p.Play(m)
+		score := -e.negamax(&next, depth-1, -beta, -alpha, ply+1)
+		if e.stopped {
+			return 0
+		}
+		if score > best {
+			best, bestMove = score, m
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			if !m.IsCapture() && m != e.killers[ply][0] {
+				e.killers[ply][1] = e.killers[ply][0]
+				e.killers[ply][0] = m
+			}
+			break
+		}
+	}
+
+	bound := boundExact
+	switch {
+	case best <= originalAlpha:
+		bound = boundUpper
+	case best >= beta:
+		bound = boundLower
+	}
+	e.tt.store(key, bestMove, best, depth, ply, bound)
+	return best
+}
+
+// Recherche de quiescence : prolonger les prises (et les parades d'échec)
+// jusqu'à une position calme pour éviter l'effet d'horizon
+func (e *Engine) quiescence(p *chess.Position, alpha, beta, ply int) int {
+	if e.shouldStop() {
+		return 0
+	}
+	e.nodes++
+
+	if outcome, over := p.Variant().Outcome(p); over {
+		return outcomeScore(outcome, p.Turn(), ply)
+	}
+	if ply >= MaxDepth-1 {
+		return e.evaluate(p)
+	}
+
+	inCheck := p.InCheck()
+	best := -Infinity
+	if !inCheck {
+		best = e.evaluate(p)
+		if best >= beta {
+			return best
+		}
+		if best > alpha {
+			alpha = best
+		}
+	}
+
+	moves := p.LegalMoves()
+	if len(moves) == 0 {
+		if inCheck {
+			return -MateScore + ply
+		}
+		return 0
+	}
+	if !inCheck {
+		tactical := moves[:0]
+		for _, m := range moves {
+			if m.IsCapture() || m.Promotion == chess.Queen {
+				tactical = append(tactical, m)
+			}
+		}
+		moves = tactical
+	}
+	e.orderMoves(p, moves, chess.NullMove, ply)
+
+	for _, m := range moves {
+		next := p.Play(m)
+		score := -e.quiescence(&next, -beta, -alpha, ply+1)
+		if e.stopped {
+			return 0
+		}
+		if score > best {
+			best = score
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// Nulle par répétition, règle des 50 coups ou matériel insuffisant
+func (e *Engine) isDraw(p *chess.Position) bool {
+	if p.HalfmoveClock() >= 100 || p.IsInsufficientMaterial() {
+		return true
+	}
+	key := p.Hash()
+	for i := len(e.path) - 2; i >= 0 && i >= len(e.path)-p.HalfmoveClock(); i -= 2 {
+		if e.path[i] == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Score d'une partie terminée du point de vue du camp au trait ; un mat proche vaut plus
+func outcomeScore(outcome chess.Outcome, turn chess.Color, ply int) int {
+	switch {
+	case outcome.Draw:
+		return 0
+	case outcome.Winner == turn:
+		return MateScore - ply
+	}
+	return -MateScore + ply
+}
+
+// Évaluation statique, bruitée selon le niveau demandé
+func (e *Engine) evaluate(p *chess.Position) int {
+	score := Evaluate(p)
+	if n := e.limits.Noise; n > 0 {
+		// Bruit déterministe par position, pour rester cohérent avec la table de transposition
+		score += int(mix(p.Hash()^e.noiseSeed)%uint64(2*n+1)) - n
+	}
+	return score
+}
+
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// Ordre des coups : coup de la table, prises (victime la plus forte par l'attaquant
+// le plus faible), promotions, coups tueurs, puis le reste
+func (e *Engine) orderMoves(p *chess.Position, moves []chess.Move, ttMove chess.Move, ply int) {
+	scores := make([]int, len(moves))
+	for i, m := range moves {
+		score := 0
+		switch {
+		case m == ttMove:
+			score = 1000000
+		case m.IsCapture():
+			victim := pieceValues[chess.Pawn]
+			if pc := p.PieceAt(m.To); pc != chess.NoPiece {
+				victim = pieceValues[pc.Type()]
+			}
+			score = 100000 + 10*victim - pieceValues[p.PieceAt(m.From).Type()]
+		case m.Promotion != chess.NoPieceType:
+			score = 90000 + pieceValues[m.Promotion]
+		case ply < MaxDepth && (m == e.killers[ply][0] || m == e.killers[ply][1]):
+			score = 80000
+		}
+		scores[i] = score
+	}
+	sort.Stable(byScore{moves, scores})
+}
+
+// Coups triés par score décroissant
+type byScore struct {
+	moves  []chess.Move
+	scores []int
+}
+
+func (s byScore) Len() int           { return len(s.moves) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s byScore) Swap(i, j int) {
+	s.moves[i], s.moves[j] = s.moves[j], s.moves[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
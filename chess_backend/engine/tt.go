@@ -0,0 +1,77 @@
+package engine
+
+import "chess_backend/chess"
+
+// Nature du score stocké : exact, borne inférieure (coupure bêta) ou borne supérieure
+type boundType uint8
+
+const (
+	boundExact boundType = iota
+	boundLower
+	boundUpper
+)
+
+type ttEntry struct {
+	key   uint64
+	move  chess.Move
+	score int32
+	depth int8
+	bound boundType
+}
+
+// Table de transposition : une entrée par index, remplacée si la recherche est plus profonde
+type transpositionTable struct {
+	entries []ttEntry
+	mask    uint64
+}
+
+func newTranspositionTable(bits uint) *transpositionTable {
+	return &transpositionTable{
+		entries: make([]ttEntry, 1<<bits),
+		mask:    1<<bits - 1,
+	}
+}
+
+func (tt *transpositionTable) probe(key uint64) (ttEntry, bool) {
+	entry := tt.entries[key&tt.mask]
+	return entry, entry.key == key && entry.depth > 0
+}
+
+func (tt *transpositionTable) store(key uint64, move chess.Move, score, depth, ply int, bound boundType) {
+	entry := &tt.entries[key&tt.mask]
+	if entry.key == key && int(entry.depth) > depth && bound != boundExact {
+		return
+	}
+	// Les scores de mat sont stockés relativement au nœud, pas à la racine
+	entry.key = key
+	entry.move = move
+	entry.score = int32(scoreToTT(score, ply))
+	entry.depth = int8(depth)
+	entry.bound = bound
+}
+
+func (tt *transpositionTable) clear() {
+	for i := range tt.entries {
+		tt.entries[i] = ttEntry{}
+	}
+}
+
+func scoreToTT(score, ply int) int {
+	switch {
+	case score > MateThreshold:
+		return score + ply
+	case score < -MateThreshold:
+		return score - ply
+	}
+	return score
+}
+
+func scoreFromTT(score, ply int) int {
+	switch {
+	case score > MateThreshold:
+		return score - ply
+	case score < -MateThreshold:
+		return score + ply
+	}
+	return score
+}
@@ -11,12 +11,20 @@ import (
 func main() {
 	router := mux.NewRouter()
 	userStore := service.SetupUserStore()
-	onlineUsersManager := service.NewOnlineUsersManager(userStore)
+	gameArchive := service.SetupGameArchive()
+	onlineUsersManager := service.NewOnlineUsersManager(userStore, gameArchive)
 
 	router.HandleFunc("/users/create", service.CreateUserHandler(userStore)).Methods("POST")
 	router.HandleFunc("/users/get", service.GetUserHandler(userStore)).Methods("GET")
 	router.HandleFunc("/users/disconnect", service.DisconnectUserHandler(userStore, onlineUsersManager)).Methods("DELETE")
 
+	// Routes des parties
+	router.HandleFunc("/games/import", service.GameImportHandler(gameArchive, userStore)).Methods("POST")
+	router.HandleFunc("/games/{id}/pgn", service.GamePGNHandler(gameArchive, onlineUsersManager)).Methods("GET")
+	router.HandleFunc("/games/{id}/review", service.GameReviewHandler(onlineUsersManager)).Methods("GET")
+	router.HandleFunc("/games/{id}/review", service.GameReviewRequestHandler(onlineUsersManager)).Methods("POST")
+	router.HandleFunc("/analysis", service.AnalysisHandler(onlineUsersManager)).Methods("GET")
+
 	// Routes WebSocket
 	router.HandleFunc("/ws", onlineUsersManager.HandleConnection)
 
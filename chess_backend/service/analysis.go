@@ -0,0 +1,533 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"chess_backend/engine"
+	"chess_backend/uci"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Messages WebSocket de l'analyse
+const (
+	AnalysisRequestMessage = "analysis_request"
+	AnalysisInfo           = "analysis_info"
+	AnalysisResultMessage  = "analysis_result"
+	AnalysisError          = "analysis_error"
+)
+
+// Bornes des paramètres d'analyse
+const (
+	defaultAnalysisDepth    = 14
+	maxAnalysisDepth        = 30
+	maxAnalysisMultiPV      = 5
+	defaultAnalysisMoveTime = 3 * time.Second
+	maxAnalysisMoveTime     = 10 * time.Second
+	analysisCacheSize       = 2000
+)
+
+// Demande d'analyse : une FEN, ou une partie (en cours de room terminée ou archivée) et un demi-coup
+type AnalysisRequest struct {
+	RequestID string `json:"requestId,omitempty"`
+	FEN       string `json:"fen,omitempty"`
+	Variant   string `json:"variant,omitempty"`
+	GameID    string `json:"gameId,omitempty"`
+	Ply       *int   `json:"ply,omitempty"` // demi-coups joués depuis le départ ; dernière position par défaut
+	Depth     int    `json:"depth,omitempty"`
+	MultiPV   int    `json:"multiPv,omitempty"`
+	MoveTime  int    `json:"moveTime,omitempty"` // millisecondes
+}
+
+// Variante calculée par le moteur
+type AnalysisLine struct {
+	Score int      `json:"score"`          // centipions, du point de vue des blancs
+	Mate  int      `json:"mate,omitempty"` // mat en N coups : positif si les blancs matent
+	UCI   []string `json:"uci"`
+	SAN   []string `json:"san"`
+}
+
+type AnalysisResult struct {
+	RequestID string         `json:"requestId,omitempty"`
+	FEN       string         `json:"fen"`
+	Variant   string         `json:"variant"`
+	Hash      string         `json:"hash"`
+	Engine    string         `json:"engine"`
+	Depth     int            `json:"depth"`
+	Nodes     uint64         `json:"nodes"`
+	BestMove  string         `json:"bestMove"`
+	Lines     []AnalysisLine `json:"lines"`
+	Cached    bool           `json:"cached"`
+	Final     bool           `json:"final"` // false pour les résultats intermédiaires
+}
+
+// Analyse de position par le moteur du serveur : le moteur UCI configuré pour les parties
+// classiques et Chess960, le moteur intégré pour les autres variantes ou à défaut
+type Analyzer struct {
+	uciPool *uci.Pool
+	engines chan *engine.Engine // moteurs intégrés libres : limite les analyses simultanées
+	cache   *analysisCache
+}
+
+func NewAnalyzer(uciPool *uci.Pool) *Analyzer {
+	workers, err := strconv.Atoi(Getenv("ANALYSIS_WORKERS", "2"))
+	if err != nil || workers < 1 {
+		workers = 2
+	}
+	engines := make(chan *engine.Engine, workers)
+	for i := 0; i < workers; i++ {
+		engines <- engine.New()
+	}
+	return &Analyzer{
+		uciPool: uciPool,
+		engines: engines,
+		cache:   newAnalysisCache(analysisCacheSize),
+	}
+}
+
+// Analyser une position ; progress reçoit les résultats intermédiaires, profondeur par profondeur
+func (a *Analyzer) Analyse(ctx context.Context, position *chess.Position, variant string, req AnalysisRequest, progress func(AnalysisResult)) (AnalysisResult, error) {
+	depth, multiPV, moveTime := analysisLimits(req)
+	key := analysisKey{variant: variant, hash: position.Hash()}
+	if cached, found := a.cache.get(key, depth, multiPV, moveTime); found {
+		cached.RequestID = req.RequestID
+		return cached, nil
+	}
+
+	base := AnalysisResult{
+		RequestID: req.RequestID,
+		FEN:       position.FEN(),
+		Variant:   variant,
+		Hash:      fmt.Sprintf("%016x", position.Hash()),
+	}
+	report := func(result AnalysisResult) {
+		if progress != nil {
+			progress(result)
+		}
+	}
+
+	var result AnalysisResult
+	var err error
+	if a.uciPool != nil && (variant == VariantStandard || variant == VariantChess960) {
+		result, err = a.analyseUCI(ctx, position, variant, base, depth, multiPV, moveTime, report)
+	} else {
+		result, err = a.analyseBuiltin(ctx, position, base, depth, multiPV, moveTime, report)
+	}
+	if err != nil {
+		return AnalysisResult{}, err
+	}
+
+	result.Final = true
+	a.cache.put(key, result, depth, moveTime)
+	return result, nil
+}
+
+// Paramètres de la demande, bornés
+func analysisLimits(req AnalysisRequest) (depth, multiPV int, moveTime time.Duration) {
+	depth = req.Depth
+	if depth <= 0 {
+		depth = defaultAnalysisDepth
+	}
+	if depth > maxAnalysisDepth {
+		depth = maxAnalysisDepth
+	}
+	multiPV = req.MultiPV
+	if multiPV <= 0 {
+		multiPV = 1
+	}
+	if multiPV > maxAnalysisMultiPV {
+		multiPV = maxAnalysisMultiPV
+	}
+	moveTime = time.Duration(req.MoveTime) * time.Millisecond
+	if moveTime <= 0 {
+		moveTime = defaultAnalysisMoveTime
+	}
+	if moveTime > maxAnalysisMoveTime {
+		moveTime = maxAnalysisMoveTime
+	}
+	return depth, multiPV, moveTime
+}
+
+func (a *Analyzer) analyseBuiltin(ctx context.Context, position *chess.Position, base AnalysisResult, depth, multiPV int, moveTime time.Duration, report func(AnalysisResult)) (AnalysisResult, error) {
+	var e *engine.Engine
+	select {
+	case e = <-a.engines:
+	case <-ctx.Done():
+		return AnalysisResult{}, ctx.Err()
+	}
+	defer func() { a.engines <- e }()
+
+	convert := func(search engine.Result) AnalysisResult {
+		result := base
+		result.Engine = "builtin"
+		result.Depth = search.Depth
+		result.Nodes = search.Nodes
+		result.BestMove = position.UCI(search.Move)
+		for _, line := range search.Lines {
+			result.Lines = append(result.Lines, builtinLine(position, line))
+		}
+		return result
+	}
+
+	search := e.Search(position, nil, engine.Limits{
+		Depth:    depth,
+		MoveTime: moveTime,
+		MultiPV:  multiPV,
+		OnDepth: func(partial engine.Result) {
+			report(convert(partial))
+		},
+	})
+	if search.Move == chess.NullMove {
+		return AnalysisResult{}, fmt.Errorf("no legal move in this position")
+	}
+	return convert(search), nil
+}
+
+func (a *Analyzer) analyseUCI(ctx context.Context, position *chess.Position, variant string, base AnalysisResult, depth, multiPV int, moveTime time.Duration, report func(AnalysisResult)) (AnalysisResult, error) {
+	if len(position.LegalMoves()) == 0 {
+		return AnalysisResult{}, fmt.Errorf("no legal move in this position")
+	}
+
+	convert := func(infos []uci.Info) AnalysisResult {
+		result := base
+		result.Engine = uciBotName()
+		for _, info := range infos {
+			if info.Depth > result.Depth {
+				result.Depth = info.Depth
+			}
+			if info.Nodes > result.Nodes {
+				result.Nodes = info.Nodes
+			}
+			result.Lines = append(result.Lines, uciLine(position, info))
+		}
+		if len(result.Lines) > 0 && len(result.Lines[0].UCI) > 0 {
+			result.BestMove = result.Lines[0].UCI[0]
+		}
+		return result
+	}
+
+	// Une profondeur est complète quand la dernière variante demandée est annoncée
+	var lines []uci.Info
+	ctx, cancel := context.WithTimeout(ctx, moveTime+uciSearchGrace)
+	defer cancel()
+	search, err := a.uciPool.Search(ctx, uci.SearchRequest{
+		GameID:   "analysis",
+		FEN:      position.FEN(),
+		Chess960: variant == VariantChess960,
+		MoveTime: moveTime,
+		Depth:    depth,
+		MultiPV:  multiPV,
+		OnInfo: func(info uci.Info) {
+			lines = setAnalysisLine(lines, info)
+			if info.MultiPV == multiPV && len(info.PV) > 0 {
+				report(convert(lines))
+			}
+		},
+	})
+	if err != nil {
+		return AnalysisResult{}, err
+	}
+
+	result := convert(search.Lines)
+	result.BestMove = search.BestMove
+	return result, nil
+}
+
+func setAnalysisLine(lines []uci.Info, info uci.Info) []uci.Info {
+	for len(lines) < info.MultiPV {
+		lines = append(lines, uci.Info{})
+	}
+	lines[info.MultiPV-1] = info
+	return lines
+}
+
+// Variante du moteur intégré, score converti du point de vue des blancs
+func builtinLine(position *chess.Position, line engine.Line) AnalysisLine {
+	result := AnalysisLine{Score: line.Score}
+	if engine.IsMateScore(line.Score) {
+		plies := engine.MateScore - abs(line.Score)
+		result.Mate = (plies + 1) / 2
+		if line.Score < 0 {
+			result.Mate = -result.Mate
+		}
+	}
+	if position.Turn() == chess.Black {
+		result.Score, result.Mate = -result.Score, -result.Mate
+	}
+
+	current := *position
+	for _, move := range line.Moves {
+		result.UCI = append(result.UCI, current.UCI(move))
+		result.SAN = append(result.SAN, current.SAN(move))
+		current = current.Play(move)
+	}
+	return result
+}
+
+// Variante d'un moteur UCI, score converti du point de vue des blancs
+func uciLine(position *chess.Position, info uci.Info) AnalysisLine {
+	result := AnalysisLine{Score: info.Score.CP, UCI: []string{}, SAN: []string{}}
+	if info.Score.IsMate {
+		result.Mate = info.Score.Mate
+		result.Score = engine.MateScore - abs(info.Score.Mate)
+		if info.Score.Mate < 0 {
+			result.Score = -result.Score
+		}
+	}
+	if position.Turn() == chess.Black {
+		result.Score, result.Mate = -result.Score, -result.Mate
+	}
+
+	current := *position
+	for _, uciMove := range info.PV {
+		move, err := current.ParseUCI(uciMove)
+		if err != nil {
+			break
+		}
+		result.UCI = append(result.UCI, uciMove)
+		result.SAN = append(result.SAN, current.SAN(move))
+		current = current.Play(move)
+	}
+	return result
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Cache des analyses par position ; une analyse n'en remplace une autre que si elle va plus loin
+type analysisKey struct {
+	variant string
+	hash    uint64
+}
+
+// Analyse en cache et limites de la recherche qui l'a produite
+type analysisEntry struct {
+	result   AnalysisResult
+	depth    int
+	moveTime time.Duration
+}
+
+// Recherche arrêtée par le temps avant d'atteindre la profondeur demandée
+func (e analysisEntry) timeLimited() bool {
+	return e.result.Depth < e.depth
+}
+
+type analysisCache struct {
+	entries map[analysisKey]analysisEntry
+	order   []analysisKey // ordre d'insertion, pour évincer les plus anciennes
+	size    int
+	mutex   sync.Mutex
+}
+
+func newAnalysisCache(size int) *analysisCache {
+	return &analysisCache{
+		entries: make(map[analysisKey]analysisEntry),
+		size:    size,
+	}
+}
+
+// Analyse en cache avec au moins multiPV variantes, allée jusqu'à depth ou arrêtée
+// par un temps de réflexion au moins égal à moveTime : la relancer n'irait pas plus loin
+func (c *analysisCache) get(key analysisKey, depth, multiPV int, moveTime time.Duration) (AnalysisResult, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, found := c.entries[key]
+	if !found || len(entry.result.Lines) < multiPV {
+		return AnalysisResult{}, false
+	}
+	if entry.result.Depth < depth && !(entry.timeLimited() && entry.moveTime >= moveTime) {
+		return AnalysisResult{}, false
+	}
+	cached := entry.result
+	cached.Lines = cached.Lines[:multiPV]
+	cached.Cached = true
+	return cached, true
+}
+
+func (c *analysisCache) put(key analysisKey, result AnalysisResult, depth int, moveTime time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result.RequestID = ""
+	entry := analysisEntry{result: result, depth: depth, moveTime: moveTime}
+	if existing, found := c.entries[key]; found {
+		if existing.result.Depth > result.Depth && len(existing.result.Lines) >= len(result.Lines) {
+			return
+		}
+		c.entries[key] = entry
+		return
+	}
+	if len(c.order) >= c.size {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.entries[key] = entry
+	c.order = append(c.order, key)
+}
+
+// Position à analyser et sa variante
+func (m *OnlineUsersManager) analysisPosition(req AnalysisRequest) (*chess.Position, string, error) {
+	if req.GameID == "" {
+		variant, err := normalizeVariant(req.Variant)
+		if err != nil {
+			return nil, "", err
+		}
+		if req.FEN == "" {
+			return nil, "", fmt.Errorf("fen or gameId is required")
+		}
+		position, err := chess.ValidateVariantFEN(variantRules(variant), req.FEN)
+		return position, variant, err
+	}
+
+	// Partie jouée sur le serveur : seulement une fois terminée
+	var game *ArchivedGame
+	if room, exists := m.roomManager.GetRoom(req.GameID); exists {
+		room.mutex.RLock()
+		over := room.IsGameOver
+		room.mutex.RUnlock()
+		if !over {
+			return nil, "", fmt.Errorf("game %s is still in progress", req.GameID)
+		}
+		snapshot := room.snapshot()
+		game = &snapshot
+	} else {
+		archived, err := m.gameArchive.GetGame(req.GameID)
+		if err != nil {
+			return nil, "", err
+		}
+		game = archived
+	}
+
+	ply := len(game.Moves)
+	if req.Ply != nil {
+		ply = *req.Ply
+	}
+	position, err := archivedPosition(game, ply)
+	return position, game.Variant, err
+}
+
+// Position d'une partie archivée après ply demi-coups
+func archivedPosition(game *ArchivedGame, ply int) (*chess.Position, error) {
+	if ply < 0 || ply > len(game.Moves) {
+		return nil, fmt.Errorf("ply %d out of range (0-%d)", ply, len(game.Moves))
+	}
+	fen := game.StartFEN
+	if ply > 0 {
+		fen = game.Moves[ply-1].FEN
+	}
+	return chess.ParseVariantFEN(variantRules(game.Variant), fen)
+}
+
+// Analyse demandée par WebSocket : résultats intermédiaires puis résultat final
+func (m *OnlineUsersManager) handleAnalysisRequest(username string, msg WebSocketMessage) {
+	var req AnalysisRequest
+	if err := json.Unmarshal([]byte(msg.Content), &req); err != nil {
+		log.Printf("Error parsing analysis request: %v", err)
+		return
+	}
+
+	sendError := func(err error) {
+		m.sendToUser(username, WebSocketMessage{
+			Type: AnalysisError,
+			Content: string(mustJson(map[string]string{
+				"requestId": req.RequestID,
+				"error":     err.Error(),
+			})),
+		})
+	}
+
+	position, variant, err := m.analysisPosition(req)
+	if err != nil {
+		sendError(err)
+		return
+	}
+
+	result, err := m.analyzer.Analyse(context.Background(), position, variant, req, func(partial AnalysisResult) {
+		m.sendToUser(username, WebSocketMessage{
+			Type:    AnalysisInfo,
+			Content: string(mustJson(partial)),
+		})
+	})
+	if err != nil {
+		sendError(err)
+		return
+	}
+	m.sendToUser(username, WebSocketMessage{
+		Type:    AnalysisResultMessage,
+		Content: string(mustJson(result)),
+	})
+}
+
+// Analyse par HTTP : GET /analysis?fen=...&variant=...&depth=...&multipv=...&movetime=...
+// ou GET /analysis?gameId=...&ply=... ; avec stream=true, une ligne JSON par profondeur
+func AnalysisHandler(onlineUsersManager *OnlineUsersManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		req := AnalysisRequest{
+			FEN:     query.Get("fen"),
+			Variant: query.Get("variant"),
+			GameID:  query.Get("gameId"),
+		}
+		for name, target := range map[string]*int{"depth": &req.Depth, "multipv": &req.MultiPV, "movetime": &req.MoveTime} {
+			if value := query.Get(name); value != "" {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("Invalid %s", name), http.StatusBadRequest)
+					return
+				}
+				*target = n
+			}
+		}
+		if value := query.Get("ply"); value != "" {
+			ply, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid ply", http.StatusBadRequest)
+				return
+			}
+			req.Ply = &ply
+		}
+
+		position, variant, err := onlineUsersManager.analysisPosition(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		flusher, canStream := w.(http.Flusher)
+		stream := canStream && query.Get("stream") == "true"
+		var progress func(AnalysisResult)
+		if stream {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			encoder := json.NewEncoder(w)
+			progress = func(partial AnalysisResult) {
+				encoder.Encode(partial)
+				flusher.Flush()
+			}
+		}
+
+		result, err := onlineUsersManager.analyzer.Analyse(r.Context(), position, variant, req, progress)
+		if err != nil {
+			if stream {
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if !stream {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Partie terminée conservée par le serveur
+type ArchivedGame struct {
+	ID          string     `json:"id"`
+	WhitePlayer OnlineUser `json:"white_player"`
+	BlackPlayer OnlineUser `json:"black_player"`
+	RoomOrigin  string     `json:"room_origin"`
+	ImportedBy  string     `json:"imported_by,omitempty"`
+	Rated       bool       `json:"rated"`
+	Variant     string     `json:"variant,omitempty"`
+	StartFEN    string     `json:"start_fen"`
+	Moves       []Move     `json:"moves"`
+	Result      string     `json:"result"`
+	Termination string     `json:"termination"`
+	WinnerID    string     `json:"winner_id,omitempty"`
+	TimeControl string     `json:"time_control"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     time.Time  `json:"ended_at"`
+
+	Tags map[string]string `json:"tags,omitempty"` // en-têtes PGN d'origine des parties importées
+
+	Review *GameReview `json:"review,omitempty"` // analyse d'après-partie, une fois terminée
+}
+
+type GameArchive struct {
+	Games map[string]ArchivedGame `json:"games"`
+	mutex sync.RWMutex
+}
+
+func NewGameArchive() *GameArchive {
+	return &GameArchive{
+		Games: make(map[string]ArchivedGame),
+		mutex: sync.RWMutex{},
+	}
+}
+
+func (ga *GameArchive) Load() error {
+	filename := filepath.Join("games", "games.json")
+
+	// Vérifier si le dossier existe
+	if err := os.MkdirAll("games", 0755); err != nil {
+		return fmt.Errorf("failed to create games directory: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		ga.Games = make(map[string]ArchivedGame)
+		return ga.Save()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read games file: %v", err)
+	}
+
+	var tempArchive struct {
+		Games map[string]ArchivedGame `json:"games"`
+	}
+	if err := json.Unmarshal(data, &tempArchive); err != nil {
+		// Ne pas écraser l'archive existante : on repart d'une archive vide en mémoire
+		log.Printf("Warning: corrupted games.json file: %v", err)
+		ga.Games = make(map[string]ArchivedGame)
+		return nil
+	}
+
+	if tempArchive.Games == nil {
+		tempArchive.Games = make(map[string]ArchivedGame)
+	}
+	ga.Games = tempArchive.Games
+	return nil
+}
+
+func (ga *GameArchive) Save() error {
+	filename := filepath.Join("games", "games.json")
+
+	tempArchive := struct {
+		Games map[string]ArchivedGame `json:"games"`
+	}{
+		Games: ga.Games,
+	}
+
+	data, err := json.MarshalIndent(tempArchive, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal games: %v", err)
+	}
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write games file: %v", err)
+	}
+	return nil
+}
+
+// Archiver une partie ; un identifiant déjà archivé est refusé plutôt qu'écrasé,
+// l'identifiant d'une room étant choisi par le client
+func (ga *GameArchive) AddGame(game ArchivedGame) error {
+	ga.mutex.Lock()
+	defer ga.mutex.Unlock()
+
+	if _, exists := ga.Games[game.ID]; exists {
+		return fmt.Errorf("game %s already archived", game.ID)
+	}
+	ga.Games[game.ID] = game
+	return ga.Save()
+}
+
+// Ajouter plusieurs parties en une seule sauvegarde ; rien n'est ajouté si un identifiant existe déjà
+func (ga *GameArchive) AddGames(games []ArchivedGame) error {
+	ga.mutex.Lock()
+	defer ga.mutex.Unlock()
+
+	ids := make(map[string]bool, len(games))
+	for _, game := range games {
+		if _, exists := ga.Games[game.ID]; exists || ids[game.ID] {
+			return fmt.Errorf("game %s already archived", game.ID)
+		}
+		ids[game.ID] = true
+	}
+	for _, game := range games {
+		ga.Games[game.ID] = game
+	}
+	return ga.Save()
+}
+
+func (ga *GameArchive) GetGame(id string) (*ArchivedGame, error) {
+	ga.mutex.RLock()
+	defer ga.mutex.RUnlock()
+
+	game, exists := ga.Games[id]
+	if !exists {
+		return nil, fmt.Errorf("game not found")
+	}
+	return &game, nil
+}
+
+// Rattacher l'analyse d'après-partie à une partie archivée
+func (ga *GameArchive) SetReview(id string, review *GameReview) error {
+	ga.mutex.Lock()
+	defer ga.mutex.Unlock()
+
+	game, exists := ga.Games[id]
+	if !exists {
+		return fmt.Errorf("game not found")
+	}
+	game.Review = review
+	ga.Games[id] = game
+	return ga.Save()
+}
+
+func SetupGameArchive() *GameArchive {
+	gameArchive := NewGameArchive()
+	if err := gameArchive.Load(); err != nil {
+		log.Printf("Warning: Error loading game archive: %v", err)
+	}
+	return gameArchive
+}
+
+// Instantané de la partie d'une room, terminée ou en cours
+func (room *ChessGameRoom) snapshot() ArchivedGame {
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+
+	moves := make([]Move, len(room.Moves))
+	copy(moves, room.Moves)
+
+	game := ArchivedGame{
+		ID:          room.RoomID,
+		WhitePlayer: room.WhitePlayer,
+		BlackPlayer: room.BlackPlayer,
+		RoomOrigin:  room.RoomOrigin,
+		Rated:       room.Rated,
+		Variant:     room.Variant,
+		StartFEN:    room.game.StartPosition().FEN(),
+		Moves:       moves,
+		Result:      room.Result,
+		Termination: room.Termination,
+		WinnerID:    room.WinnerID,
+		TimeControl: room.TimeControl.PGN(),
+		StartedAt:   room.CreatedAt,
+		EndedAt:     time.Now(),
+	}
+	if game.Result == "" {
+		game.Result = "*"
+	}
+	return game
+}
+
+// Archiver la partie d'une room terminée
+func (rm *RoomManager) archiveRoom(room *ChessGameRoom) {
+	if rm.onlineManager == nil || rm.onlineManager.gameArchive == nil {
+		return
+	}
+
+	room.mutex.RLock()
+	finished := room.Status == RoomStatusFinished
+	room.mutex.RUnlock()
+	if !finished {
+		return
+	}
+
+	game := room.snapshot()
+	if err := rm.onlineManager.gameArchive.AddGame(game); err != nil {
+		log.Printf("Error archiving game %s: %v", room.RoomID, err)
+		return
+	}
+
+	// Lancer l'analyse d'après-partie ; les deux joueurs suivent sa progression
+	if rm.onlineManager.reviewer != nil && len(game.Moves) > 0 {
+		rm.onlineManager.reviewer.Enqueue(game.ID, game.WhitePlayer.Username, game.BlackPlayer.Username)
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"chess_backend/engine"
+	"chess_backend/uci"
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Adversaires virtuels invitables comme des utilisateurs : le moteur intégré,
+// et un moteur UCI installé sur le serveur s'il est configuré
+const (
+	BotUsername       = "computer"
+	BotUserID         = "bot"
+	DefaultUCIBotName = "engine"
+	UCIBotUserID      = "uci_bot"
+)
+
+// Délai laissé au moteur UCI au-delà de son temps de réflexion
+const uciSearchGrace = 2 * time.Second
+
+// Coups supposés restant à jouer pour répartir le temps de la pendule
+const botMovesToGo = 30
+
+// Marge gardée sur la pendule pour l'envoi du coup
+const botClockMargin = 500 * time.Millisecond
+
+type BotPlayer struct {
+	room   *ChessGameRoom
+	user   OnlineUser
+	engine *engine.Engine // moteur intégré, ou nil pour un moteur UCI
+	uci    *uci.Pool
+	level  int
+	color  chess.Color
+	mutex  sync.Mutex // une seule réflexion à la fois
+}
+
+// Pool de moteurs UCI configuré par UCI_ENGINE_PATH, ou nil
+func setupUCIPool() *uci.Pool {
+	path := Getenv("UCI_ENGINE_PATH", "")
+	if path == "" {
+		return nil
+	}
+	size, err := strconv.Atoi(Getenv("UCI_POOL_SIZE", "2"))
+	if err != nil {
+		log.Printf("Warning: invalid UCI_POOL_SIZE, using 2: %v", err)
+		size = 2
+	}
+
+	// Options sous la forme "Hash=64,Threads=1"
+	options := make(map[string]string)
+	for _, option := range strings.Split(Getenv("UCI_ENGINE_OPTIONS", ""), ",") {
+		if name, value, found := strings.Cut(option, "="); found {
+			options[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+	log.Printf("UCI engine %s available with %d processes", path, size)
+	return uci.NewPool(path, size, options)
+}
+
+// Nom du moteur UCI dans la liste des utilisateurs
+func uciBotName() string {
+	return Getenv("UCI_ENGINE_NAME", DefaultUCIBotName)
+}
+
+// Noms réservés aux adversaires virtuels
+func isBotUsername(username string) bool {
+	return strings.EqualFold(username, BotUsername) || strings.EqualFold(username, uciBotName())
+}
+
+// Indique si username désigne un adversaire virtuel disponible
+func (m *OnlineUsersManager) isBotAvailable(username string) bool {
+	for _, bot := range m.botUsers() {
+		if bot.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// Adversaires virtuels toujours disponibles
+func (m *OnlineUsersManager) botUsers() []OnlineUser {
+	bots := []OnlineUser{{ID: BotUserID, Username: BotUsername}}
+	if m.uciPool != nil {
+		bots = append(bots, OnlineUser{ID: UCIBotUserID, Username: uciBotName()})
+	}
+	return bots
+}
+
+// Partie contre l'ordinateur : le bot accepte aussitôt et prend les noirs
+func (m *OnlineUsersManager) startBotGame(invitation InvitationMessage) error {
+	bot := &BotPlayer{
+		user:  OnlineUser{ID: BotUserID, Username: BotUsername},
+		color: chess.Black,
+	}
+	if invitation.ToUsername == BotUsername {
+		bot.engine = engine.New()
+	} else {
+		bot.user = OnlineUser{ID: UCIBotUserID, Username: uciBotName()}
+		bot.uci = m.uciPool
+	}
+
+	level := invitation.BotLevel
+	if level == 0 {
+		level = engine.DefaultLevel
+	}
+	if _, err := engine.LevelFor(level); err != nil {
+		m.sendInvitationError(invitation, err)
+		return err
+	}
+
+	variant, err := normalizeVariant(invitation.Variant)
+	if err == nil && variant == VariantFogOfWar {
+		err = fmt.Errorf("the computer does not play fog of war")
+	}
+	if err == nil && bot.uci != nil && variant != VariantStandard && variant != VariantChess960 {
+		err = fmt.Errorf("%s only plays standard chess and chess960", bot.user.Username)
+	}
+	if err != nil {
+		m.sendInvitationError(invitation, err)
+		return err
+	}
+	invitation.Variant = variant
+
+	timeControl, err := ParseTimeControl(invitation.TimeControl)
+	if err != nil {
+		m.sendInvitationError(invitation, err)
+		return err
+	}
+	invitation.TimeControl = timeControl.String()
+
+	if invitation.FEN != "" {
+		fen, err := validateVariantFEN(invitation.Variant, invitation.FEN)
+		if err != nil {
+			m.sendInvitationError(invitation, err)
+			return err
+		}
+		invitation.FEN = fen
+	}
+
+	m.mutex.RLock()
+	conn, exists := m.connections[invitation.FromUsername]
+	m.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("user not online")
+	}
+
+	invitation.Type = InvitationAccept
+	invitation.RoomID = GenerateUniqueID()
+	invitation.ToUserID = bot.user.ID
+	invitation.ToUsername = bot.user.Username
+	invitation.BotLevel = level
+	invitation.Rated = false
+	room := m.roomManager.CreateRoom(invitation)
+
+	bot.room, bot.level = room, level
+	room.mutex.Lock()
+	room.RoomOrigin = "bot"
+	room.bot = bot
+	room.mutex.Unlock()
+
+	m.userStore.UpdateUserRoomStatus(invitation.FromUsername, true)
+	room.AddConnection(invitation.FromUsername, conn)
+
+	room.mutex.RLock()
+	gameState := map[string]interface{}{
+		"gameId":         room.RoomID,
+		"gameCreatorUid": invitation.FromUserID,
+		"positonFen":     room.PositionFEN,
+		"winnerId":       "",
+		"whitesTime":     room.WhitesTime,
+		"blacksTime":     room.BlacksTime,
+		"isWhitesTurn":   room.IsWhitesTurn,
+		"isGameOver":     room.IsGameOver,
+		"moves":          room.Moves,
+		"variant":        room.Variant,
+		"timeControl":    room.TimeControl,
+		"clock":          room.clockState(),
+		"botLevel":       level,
+	}
+	room.mutex.RUnlock()
+
+	conn.Send(WebSocketMessage{
+		Type:    "game_start",
+		Content: string(mustJson(copyAndAddUserInfo(gameState, invitation.FromUserID, bot.user.Username))),
+	})
+	m.broadcastOnlineUsers()
+
+	// Position personnalisée avec les noirs au trait : le bot commence
+	go bot.play()
+	return nil
+}
+
+// Chercher et jouer un coup si c'est au bot de jouer
+func (bot *BotPlayer) play() {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+
+	room := bot.room
+	room.mutex.RLock()
+	if room.IsGameOver || room.game.Position().Turn() != bot.color {
+		room.mutex.RUnlock()
+		return
+	}
+	// Copier la position : la recherche se fait sans tenir le verrou de la room
+	position := *room.game.Position()
+	history := make([]uint64, 0, len(room.Moves))
+	moves := make([]string, 0, len(room.Moves))
+	for ply, move := range room.game.Moves() {
+		before := room.game.PositionAt(ply)
+		history = append(history, before.Hash())
+		moves = append(moves, before.UCI(move))
+	}
+	startFEN := room.game.StartPosition().FEN()
+	chess960 := room.Variant == VariantChess960
+	human, gameID := room.WhitePlayer, room.RoomID
+	if bot.color == chess.White {
+		human = room.BlackPlayer
+	}
+	room.mutex.RUnlock()
+
+	level, _ := engine.LevelFor(bot.level)
+	limits := level.Limits(bot.timeBudget())
+
+	var move chess.Move
+	if bot.uci != nil {
+		var err error
+		if move, err = bot.searchUCI(&position, gameID, startFEN, moves, chess960, limits); err != nil {
+			log.Printf("UCI engine failed in room %s: %v", gameID, err)
+			return
+		}
+	} else {
+		move = bot.engine.Search(&position, history, limits).Move
+	}
+	if move == chess.NullMove {
+		return
+	}
+
+	err := room.SendMove(bot.user.Username, MoveData{
+		GameID:     gameID,
+		FromUserID: bot.user.ID,
+		ToUserID:   human.ID,
+		ToUsername: human.Username,
+		Move:       position.UCI(move),
+	}, time.Now())
+	if err != nil {
+		log.Printf("Bot move %s failed in room %s: %v", position.UCI(move), gameID, err)
+	}
+}
+
+// Demander un coup au moteur UCI, avec les pendules de la room
+func (bot *BotPlayer) searchUCI(position *chess.Position, gameID, startFEN string, moves []string, chess960 bool, limits engine.Limits) (chess.Move, error) {
+	req := uci.SearchRequest{
+		GameID:   gameID,
+		FEN:      startFEN,
+		Moves:    moves,
+		Chess960: chess960,
+		MoveTime: limits.MoveTime,
+	}
+	if limits.Depth < engine.MaxDepth {
+		req.Depth = limits.Depth
+	}
+	if bot.room.Timer != nil {
+		req.WTime, req.BTime = bot.room.Timer.Remaining()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), limits.MoveTime+uciSearchGrace)
+	defer cancel()
+	result, err := bot.uci.Search(ctx, req)
+	if err != nil {
+		return chess.NullMove, err
+	}
+	return position.ParseUCI(result.BestMove)
+}
+
+// Temps de réflexion accordé par la pendule : une part du temps restant
+func (bot *BotPlayer) timeBudget() time.Duration {
+	if bot.room.Timer == nil {
+		return 0
+	}
+	remaining, blackTime := bot.room.Timer.Remaining()
+	if bot.color == chess.Black {
+		remaining = blackTime
+	}
+
+	// L'incrément (ou le délai) est regagné après chaque coup : on peut en dépenser l'essentiel
+	increment := time.Duration(bot.room.TimeControl.Stages[0].Increment) * time.Second
+	budget := remaining/botMovesToGo + increment*3/4
+	if budget > remaining-botClockMargin {
+		budget = remaining - botClockMargin
+	}
+	if budget < 10*time.Millisecond {
+		budget = 10 * time.Millisecond
+	}
+	return budget
+}
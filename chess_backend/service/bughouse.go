@@ -0,0 +1,424 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BughouseInvite string = "bughouse_invite"
+	BughouseAccept string = "bughouse_accept"
+	BughouseReject string = "bughouse_reject"
+	BughouseCancel string = "bughouse_cancel"
+	BughouseError  string = "bughouse_error"
+	PocketUpdate   string = "pocket_update"
+	PartnerMove    string = "partner_move"
+	PartnerChat    string = "partner_chat"
+)
+
+// Fin d'un échiquier provoquée par le résultat de l'autre
+const PartnerBoardTermination chess.Termination = "partner_board"
+
+// Délai pour que les quatre joueurs acceptent
+const bughouseInviteTimeout = 30 * time.Second
+
+const maxPartnerChatLength = 500
+
+// Places d'une partie de bughouse : échiquier A (blancs, noirs) puis échiquier B (blancs, noirs).
+// Les équipes sont A blancs + B noirs et A noirs + B blancs.
+type BughouseSeats [4]OnlineUser
+
+// Invitation en attente des réponses des trois autres joueurs
+type pendingBughouse struct {
+	MatchID     string
+	Seats       BughouseSeats
+	TimeControl TimeControl
+	accepted    map[string]bool
+	timer       *time.Timer
+}
+
+type BughouseManager struct {
+	pending map[string]*pendingBughouse
+	mutex   sync.Mutex
+}
+
+func NewBughouseManager() *BughouseManager {
+	return &BughouseManager{
+		pending: make(map[string]*pendingBughouse),
+	}
+}
+
+func (seats BughouseSeats) usernames() []string {
+	return []string{seats[0].Username, seats[1].Username, seats[2].Username, seats[3].Username}
+}
+
+// Traiter les messages bughouse_invite, bughouse_accept et bughouse_reject
+func (m *OnlineUsersManager) handleBughouseMessage(username string, msg WebSocketMessage) {
+	var data struct {
+		MatchID     string   `json:"matchId"`
+		Partner     string   `json:"partner"`
+		Opponents   []string `json:"opponents"`
+		TimeControl string   `json:"time_control"`
+	}
+	if err := json.Unmarshal([]byte(msg.Content), &data); err != nil {
+		log.Printf("Error parsing bughouse message: %v", err)
+		return
+	}
+
+	var err error
+	switch msg.Type {
+	case BughouseInvite:
+		err = m.inviteBughouse(username, data.Partner, data.Opponents, data.TimeControl)
+	case BughouseAccept:
+		err = m.acceptBughouse(username, data.MatchID)
+	case BughouseReject:
+		err = m.rejectBughouse(username, data.MatchID)
+	}
+
+	if err != nil {
+		log.Printf("Error handling %s from %s: %v", msg.Type, username, err)
+		m.sendToUser(username, WebSocketMessage{
+			Type: BughouseError,
+			Content: string(mustJson(map[string]string{
+				"matchId": data.MatchID,
+				"error":   err.Error(),
+			})),
+		})
+	}
+}
+
+// Le créateur invite son partenaire et deux adversaires ; il joue les blancs sur l'échiquier A
+func (m *OnlineUsersManager) inviteBughouse(username, partner string, opponents []string, timeControlName string) error {
+	if len(opponents) != 2 {
+		return fmt.Errorf("bughouse needs a partner and two opponents")
+	}
+	timeControl, err := ParseTimeControl(timeControlName)
+	if err != nil {
+		return err
+	}
+
+	players := []string{username, opponents[0], opponents[1], partner}
+	var seats BughouseSeats
+	seen := make(map[string]bool)
+	for i, name := range players {
+		if name == "" || seen[name] {
+			return fmt.Errorf("bughouse needs four different players")
+		}
+		seen[name] = true
+
+		user, err := m.userStore.GetUser(name)
+		if err != nil {
+			return fmt.Errorf("user %s not found", name)
+		}
+		if user.IsInRoom {
+			return fmt.Errorf("user %s is already in a game", name)
+		}
+		m.mutex.RLock()
+		_, online := m.connections[name]
+		m.mutex.RUnlock()
+		if !online {
+			return fmt.Errorf("user %s is not online", name)
+		}
+		seats[i] = OnlineUser{ID: user.ID, Username: user.UserName}
+	}
+
+	invite := &pendingBughouse{
+		MatchID:     GenerateUniqueID(),
+		Seats:       seats,
+		TimeControl: timeControl,
+		accepted:    map[string]bool{username: true},
+	}
+	invite.timer = time.AfterFunc(bughouseInviteTimeout, func() {
+		m.cancelBughouse(invite.MatchID, "timeout")
+	})
+
+	m.bughouse.mutex.Lock()
+	m.bughouse.pending[invite.MatchID] = invite
+	m.bughouse.mutex.Unlock()
+
+	message := WebSocketMessage{
+		Type: BughouseInvite,
+		Content: string(mustJson(map[string]interface{}{
+			"matchId":      invite.MatchID,
+			"fromUsername": username,
+			"seats":        seats.usernames(),
+			"timeControl":  timeControl,
+		})),
+	}
+	for _, name := range seats.usernames() {
+		m.sendToUser(name, message)
+	}
+	return nil
+}
+
+func (m *OnlineUsersManager) acceptBughouse(username, matchID string) error {
+	m.bughouse.mutex.Lock()
+	invite, exists := m.bughouse.pending[matchID]
+	if !exists {
+		m.bughouse.mutex.Unlock()
+		return fmt.Errorf("bughouse invitation not found")
+	}
+	if !invite.hasPlayer(username) {
+		m.bughouse.mutex.Unlock()
+		return fmt.Errorf("user %s not invited", username)
+	}
+	invite.accepted[username] = true
+	ready := len(invite.accepted) == len(invite.Seats)
+	if ready {
+		invite.timer.Stop()
+		delete(m.bughouse.pending, matchID)
+	}
+	m.bughouse.mutex.Unlock()
+
+	if ready {
+		m.startBughouse(invite)
+	}
+	return nil
+}
+
+func (m *OnlineUsersManager) rejectBughouse(username, matchID string) error {
+	m.bughouse.mutex.Lock()
+	invite, exists := m.bughouse.pending[matchID]
+	m.bughouse.mutex.Unlock()
+	if !exists || !invite.hasPlayer(username) {
+		return fmt.Errorf("bughouse invitation not found")
+	}
+	m.cancelBughouse(matchID, fmt.Sprintf("rejected by %s", username))
+	return nil
+}
+
+// Annuler une invitation et prévenir les quatre joueurs
+func (m *OnlineUsersManager) cancelBughouse(matchID, reason string) {
+	m.bughouse.mutex.Lock()
+	invite, exists := m.bughouse.pending[matchID]
+	if exists {
+		invite.timer.Stop()
+		delete(m.bughouse.pending, matchID)
+	}
+	m.bughouse.mutex.Unlock()
+	if !exists {
+		return
+	}
+
+	message := WebSocketMessage{
+		Type: BughouseCancel,
+		Content: string(mustJson(map[string]string{
+			"matchId": matchID,
+			"reason":  reason,
+		})),
+	}
+	for _, name := range invite.Seats.usernames() {
+		m.sendToUser(name, message)
+	}
+}
+
+// Un joueur qui se déconnecte annule les invitations qui le concernent
+func (m *OnlineUsersManager) cancelBughouseInvitesFor(username string) {
+	m.bughouse.mutex.Lock()
+	var matchIDs []string
+	for matchID, invite := range m.bughouse.pending {
+		if invite.hasPlayer(username) {
+			matchIDs = append(matchIDs, matchID)
+		}
+	}
+	m.bughouse.mutex.Unlock()
+
+	for _, matchID := range matchIDs {
+		m.cancelBughouse(matchID, fmt.Sprintf("%s disconnected", username))
+	}
+}
+
+func (invite *pendingBughouse) hasPlayer(username string) bool {
+	for _, seat := range invite.Seats {
+		if seat.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// Créer les deux rooms liées et lancer les quatre pendules
+func (m *OnlineUsersManager) startBughouse(invite *pendingBughouse) {
+	seats := invite.Seats
+	boards := [2]*ChessGameRoom{}
+	for i := range boards {
+		white, black := seats[2*i], seats[2*i+1]
+		boards[i] = m.roomManager.CreateRoom(InvitationMessage{
+			Type:         InvitationAccept,
+			FromUserID:   white.ID,
+			FromUsername: white.Username,
+			ToUserID:     black.ID,
+			ToUsername:   black.Username,
+			RoomID:       GenerateUniqueID(),
+			Variant:      VariantBughouse,
+			TimeControl:  invite.TimeControl.String(),
+		})
+	}
+	for i, room := range boards {
+		room.mutex.Lock()
+		room.RoomOrigin = "bughouse"
+		room.partner = boards[1-i]
+		room.mutex.Unlock()
+	}
+
+	for i, seat := range seats {
+		m.userStore.UpdateUserRoomStatus(seat.Username, true)
+
+		room, partnerRoom := boards[i/2], boards[1-i/2]
+		opponent, _ := room.GetOtherPlayer(seat.Username)
+		teammate, _ := room.teammate(seat.Username)
+
+		room.mutex.RLock()
+		state := map[string]interface{}{
+			"gameId":         room.RoomID,
+			"gameCreatorUid": room.WhitePlayer.ID,
+			"positonFen":     room.PositionFEN,
+			"winnerId":       "",
+			"whitesTime":     room.WhitesTime,
+			"blacksTime":     room.BlacksTime,
+			"isWhitesTurn":   room.IsWhitesTurn,
+			"isGameOver":     false,
+			"moves":          room.Moves,
+			"variant":        room.Variant,
+			"timeControl":    room.TimeControl,
+			"clock":          room.clockState(),
+			"pockets":        room.Pockets,
+			"matchId":        invite.MatchID,
+			"board":          string(rune('A' + i/2)),
+			"partnerGameId":  partnerRoom.RoomID,
+			"partner":        teammate,
+		}
+		room.mutex.RUnlock()
+
+		m.mutex.RLock()
+		conn, exists := m.connections[seat.Username]
+		m.mutex.RUnlock()
+		if !exists {
+			continue
+		}
+		room.AddConnection(seat.Username, conn)
+		conn.Send(WebSocketMessage{
+			Type:    "game_start",
+			Content: string(mustJson(copyAndAddUserInfo(state, seat.ID, opponent))),
+		})
+	}
+
+	m.broadcastOnlineUsers()
+}
+
+// Partenaire d'un joueur : il joue l'autre couleur sur l'échiquier lié
+func (room *ChessGameRoom) teammate(username string) (string, bool) {
+	room.mutex.RLock()
+	color, found := room.playerColor(username)
+	partner := room.partner
+	room.mutex.RUnlock()
+	if !found || partner == nil {
+		return "", false
+	}
+
+	partner.mutex.RLock()
+	defer partner.mutex.RUnlock()
+	if color == chess.White {
+		return partner.BlackPlayer.Username, true
+	}
+	return partner.WhitePlayer.Username, true
+}
+
+// Recevoir dans la réserve du camp c une pièce prise sur l'échiquier lié
+func (room *ChessGameRoom) receivePiece(c chess.Color, pt chess.PieceType) {
+	room.mutex.Lock()
+	if room.IsGameOver {
+		room.mutex.Unlock()
+		return
+	}
+	room.game.AddToPocket(c, pt)
+	room.PositionFEN = room.game.Position().FEN()
+	room.Pockets = pocketsOf(room.game.Position())
+	message := WebSocketMessage{
+		Type: PocketUpdate,
+		Content: string(mustJson(map[string]interface{}{
+			"gameId":  room.RoomID,
+			"fen":     room.PositionFEN,
+			"pockets": room.Pockets,
+		})),
+	}
+	room.mutex.Unlock()
+
+	room.BroadcastMessage(message)
+}
+
+// Coup joué dans la room, à destination des joueurs de l'échiquier lié.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) partnerMoveMessage(record Move) WebSocketMessage {
+	return WebSocketMessage{
+		Type: PartnerMove,
+		Content: string(mustJson(map[string]interface{}{
+			"gameId":       room.RoomID,
+			"uci":          record.UCI,
+			"san":          record.SAN,
+			"fen":          room.PositionFEN,
+			"isWhitesTurn": room.IsWhitesTurn,
+			"pockets":      room.Pockets,
+		})),
+	}
+}
+
+// Résultat de l'échiquier lié : l'équipe gagnante y joue l'autre couleur
+func partnerOutcome(outcome chess.Outcome) chess.Outcome {
+	if outcome.Draw {
+		return chess.Outcome{Termination: PartnerBoardTermination, Draw: true}
+	}
+	return chess.Outcome{Termination: PartnerBoardTermination, Winner: outcome.Winner.Other()}
+}
+
+// Message réservé au partenaire, qui joue sur l'autre échiquier
+func (m *OnlineUsersManager) handlePartnerChat(username string, msg WebSocketMessage) {
+	var chatData struct {
+		GameID  string `json:"gameId"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(msg.Content), &chatData); err != nil {
+		log.Printf("Error parsing partner chat message: %v", err)
+		return
+	}
+
+	text := strings.TrimSpace(chatData.Message)
+	if text == "" || len(text) > maxPartnerChatLength {
+		return
+	}
+
+	room, exists := m.roomManager.GetRoom(chatData.GameID)
+	if !exists {
+		log.Printf("Room not found: %s", chatData.GameID)
+		return
+	}
+	teammate, found := room.teammate(username)
+	if !found {
+		log.Printf("No bughouse partner for %s in room %s", username, chatData.GameID)
+		return
+	}
+
+	m.sendToUser(teammate, WebSocketMessage{
+		Type: PartnerChat,
+		Content: string(mustJson(map[string]string{
+			"gameId":       chatData.GameID,
+			"fromUsername": username,
+			"message":      text,
+		})),
+	})
+}
+
+// Envoyer un message à un utilisateur connecté
+func (m *OnlineUsersManager) sendToUser(username string, message WebSocketMessage) {
+	m.mutex.RLock()
+	conn, exists := m.connections[username]
+	m.mutex.RUnlock()
+	if exists {
+		conn.Send(message)
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// Messages de synchronisation des pendules
+const (
+	ClockPing = "clock_ping" // serveur -> client : {"id", "serverTime"}
+	ClockPong = "clock_pong" // client -> serveur : {"id", "clientTime"}, renvoyé dès réception du ping
+	ClockSync = "clock_sync" // serveur -> client : latence estimée et décalage de l'horloge du client
+)
+
+const (
+	clockPingInterval = 5 * time.Second
+	clockPingExpiry   = 30 * time.Second // un pong plus tardif est ignoré
+	rttSmoothing      = 0.25             // poids de la dernière mesure dans la moyenne glissante
+)
+
+// Compensation du décalage réseau : chaque coup peut être crédité de la latence estimée,
+// dans la limite d'un plafond par coup et d'un quota par partie qui se reconstitue à chaque coup
+const (
+	maxLagAllowance = 500 * time.Millisecond
+	lagQuotaInitial = 2 * time.Second
+	lagQuotaGain    = 250 * time.Millisecond
+	lagQuotaMax     = 5 * time.Second
+)
+
+// Latence mesurée sur la connexion d'un joueur
+type connectionLag struct {
+	rtt     time.Duration
+	offset  time.Duration // horloge du client moins horloge du serveur
+	samples int
+	pending map[int64]time.Time // pings envoyés, par identifiant
+	nextID  int64
+}
+
+type LagMonitor struct {
+	connections map[string]*connectionLag
+	mutex       sync.Mutex
+}
+
+func NewLagMonitor() *LagMonitor {
+	return &LagMonitor{connections: make(map[string]*connectionLag)}
+}
+
+// Repartir de zéro pour une nouvelle connexion du joueur
+func (lm *LagMonitor) start(username string) *connectionLag {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	lag := &connectionLag{pending: make(map[int64]time.Time)}
+	lm.connections[username] = lag
+	return lag
+}
+
+// Oublier la connexion, sauf si le joueur s'est reconnecté entre-temps
+func (lm *LagMonitor) stop(username string, lag *connectionLag) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	if lm.connections[username] == lag {
+		delete(lm.connections, username)
+	}
+}
+
+// Préparer un ping : identifiant et instant d'envoi
+func (lm *LagMonitor) ping(username string) (int64, time.Time, bool) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	lag, exists := lm.connections[username]
+	if !exists {
+		return 0, time.Time{}, false
+	}
+	now := time.Now()
+	for id, sent := range lag.pending {
+		if now.Sub(sent) > clockPingExpiry {
+			delete(lag.pending, id)
+		}
+	}
+	lag.nextID++
+	lag.pending[lag.nextID] = now
+	return lag.nextID, now, true
+}
+
+// Enregistrer le pong reçu à l'instant receivedAt et mettre à jour les estimations
+func (lm *LagMonitor) pong(username string, id int64, clientTime time.Time, receivedAt time.Time) (connectionLag, bool) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+
+	lag, exists := lm.connections[username]
+	if !exists {
+		return connectionLag{}, false
+	}
+	sent, found := lag.pending[id]
+	if !found {
+		return connectionLag{}, false
+	}
+	delete(lag.pending, id)
+
+	rtt := receivedAt.Sub(sent)
+	// Le client a répondu à mi-parcours : son horloge marquait clientTime à sent + rtt/2
+	offset := clientTime.Sub(sent.Add(rtt / 2))
+	if lag.samples == 0 {
+		lag.rtt, lag.offset = rtt, offset
+	} else {
+		lag.rtt += time.Duration(rttSmoothing * float64(rtt-lag.rtt))
+		lag.offset += time.Duration(rttSmoothing * float64(offset-lag.offset))
+	}
+	lag.samples++
+	return *lag, true
+}
+
+// Latence aller estimée de la connexion du joueur (0 tant qu'aucune mesure n'est disponible)
+func (lm *LagMonitor) Lag(username string) time.Duration {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	if lag, exists := lm.connections[username]; exists && lag.samples > 0 {
+		return lag.rtt / 2
+	}
+	return 0
+}
+
+// Envoyer un ping régulier au joueur tant que sa connexion est ouverte
+func (m *OnlineUsersManager) runClockSync(username string, done <-chan struct{}) {
+	lag := m.lagMonitor.start(username)
+	defer m.lagMonitor.stop(username, lag)
+
+	ticker := time.NewTicker(clockPingInterval)
+	defer ticker.Stop()
+	for {
+		if id, sent, ok := m.lagMonitor.ping(username); ok {
+			m.sendToUser(username, WebSocketMessage{
+				Type: ClockPing,
+				Content: string(mustJson(map[string]int64{
+					"id":         id,
+					"serverTime": sent.UnixMilli(),
+				})),
+			})
+		}
+
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// Réponse du client à un ping : renvoyer la latence et le décalage d'horloge estimés
+func (m *OnlineUsersManager) handleClockPong(username string, msg WebSocketMessage, receivedAt time.Time) {
+	var pong struct {
+		ID         int64 `json:"id"`
+		ClientTime int64 `json:"clientTime"` // millisecondes depuis l'epoch Unix
+	}
+	if err := json.Unmarshal([]byte(msg.Content), &pong); err != nil {
+		log.Printf("Error parsing clock pong: %v", err)
+		return
+	}
+
+	lag, ok := m.lagMonitor.pong(username, pong.ID, time.UnixMilli(pong.ClientTime), receivedAt)
+	if !ok {
+		return
+	}
+	m.sendToUser(username, WebSocketMessage{
+		Type: ClockSync,
+		Content: string(mustJson(map[string]int64{
+			"rtt":        lag.rtt.Milliseconds(),
+			"lag":        (lag.rtt / 2).Milliseconds(),
+			"offset":     lag.offset.Milliseconds(),
+			"serverTime": time.Now().UnixMilli(),
+		})),
+	})
+}
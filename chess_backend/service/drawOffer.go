@@ -0,0 +1,136 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"fmt"
+)
+
+const (
+	DrawOffer        string = "draw_offer"
+	DrawAccept       string = "draw_accept"
+	DrawDecline      string = "draw_decline"
+	DrawDeclined     string = "draw_declined"
+	DrawOfferExpired string = "draw_offer_expired"
+	DrawError        string = "draw_error"
+)
+
+// Proposer la nulle à l'adversaire ; une seule proposition peut être en attente
+func (room *ChessGameRoom) OfferDraw(username string) error {
+	room.mutex.Lock()
+	if room.IsGameOver {
+		room.mutex.Unlock()
+		return fmt.Errorf("game is over")
+	}
+	opponent, found := room.GetOtherPlayer(username)
+	if !found {
+		room.mutex.Unlock()
+		return fmt.Errorf("user %s not in room %s", username, room.RoomID)
+	}
+	if room.drawOfferBy != "" {
+		room.mutex.Unlock()
+		return fmt.Errorf("a draw offer is already pending")
+	}
+	room.drawOfferBy = username
+	room.mutex.Unlock()
+
+	room.sendToPlayer(opponent, WebSocketMessage{
+		Type: DrawOffer,
+		Content: string(mustJson(map[string]string{
+			"gameId":       room.RoomID,
+			"fromUsername": username,
+		})),
+	})
+	return nil
+}
+
+// Accepter la nulle proposée par l'adversaire
+func (room *ChessGameRoom) AcceptDraw(username string) error {
+	room.mutex.Lock()
+	if room.IsGameOver {
+		room.mutex.Unlock()
+		return fmt.Errorf("game is over")
+	}
+	if _, found := room.playerColor(username); !found {
+		room.mutex.Unlock()
+		return fmt.Errorf("user %s not in room %s", username, room.RoomID)
+	}
+	if room.drawOfferBy == "" || room.drawOfferBy == username {
+		room.mutex.Unlock()
+		return fmt.Errorf("no draw offer to accept")
+	}
+	room.drawOfferBy = ""
+	room.IsGameOver = true
+	room.mutex.Unlock()
+
+	room.finishGame(chess.Outcome{Termination: chess.DrawAgreement, Draw: true})
+	return nil
+}
+
+// Refuser la nulle proposée par l'adversaire
+func (room *ChessGameRoom) DeclineDraw(username string) error {
+	room.mutex.Lock()
+	if _, found := room.playerColor(username); !found {
+		room.mutex.Unlock()
+		return fmt.Errorf("user %s not in room %s", username, room.RoomID)
+	}
+	offeredBy := room.drawOfferBy
+	if offeredBy == "" || offeredBy == username {
+		room.mutex.Unlock()
+		return fmt.Errorf("no draw offer to decline")
+	}
+	room.drawOfferBy = ""
+	room.mutex.Unlock()
+
+	room.sendToPlayer(offeredBy, WebSocketMessage{
+		Type: DrawDeclined,
+		Content: string(mustJson(map[string]string{
+			"gameId":       room.RoomID,
+			"fromUsername": username,
+		})),
+	})
+	return nil
+}
+
+// Une proposition expire dès que l'adversaire de celui qui l'a faite joue.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) expireDrawOffer(mover string) {
+	if room.drawOfferBy == "" || room.drawOfferBy == mover {
+		return
+	}
+	room.drawOfferBy = ""
+
+	message := WebSocketMessage{
+		Type: DrawOfferExpired,
+		Content: string(mustJson(map[string]string{
+			"gameId": room.RoomID,
+		})),
+	}
+	for _, conn := range room.Connections {
+		conn.Send(message)
+	}
+}
+
+func (room *ChessGameRoom) sendToPlayer(username string, message WebSocketMessage) {
+	room.mutex.RLock()
+	conn, exists := room.Connections[username]
+	room.mutex.RUnlock()
+
+	if exists {
+		conn.Send(message)
+	}
+}
+
+// Traiter les messages draw_offer, draw_accept et draw_decline
+func (m *OnlineUsersManager) handleDrawMessage(username string, msg WebSocketMessage) {
+	m.handleRoomAction(username, msg, DrawError, func(room *ChessGameRoom) error {
+		switch msg.Type {
+		case DrawOffer:
+			return room.OfferDraw(username)
+		case DrawAccept:
+			return room.AcceptDraw(username)
+		case DrawDecline:
+			return room.DeclineDraw(username)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+)
+
+// Room de test entre alice (blancs) et bob (noirs), sans pendule ni connexion
+func newTestRoom(t *testing.T) *ChessGameRoom {
+	t.Helper()
+	game, err := newVariantGame(VariantStandard, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &ChessGameRoom{
+		RoomID:       "test",
+		WhitePlayer:  OnlineUser{ID: "1", Username: "alice"},
+		BlackPlayer:  OnlineUser{ID: "2", Username: "bob"},
+		Connections:  make(map[string]*SafeConn),
+		Status:       RoomStatusInGame,
+		Variant:      VariantStandard,
+		IsWhitesTurn: true,
+		game:         game,
+	}
+}
+
+func TestDrawOfferRefusedToNonPlayer(t *testing.T) {
+	room := newTestRoom(t)
+	if err := room.OfferDraw("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := room.AcceptDraw("mallory"); err == nil {
+		t.Error("a user outside the room accepted the draw offer")
+	}
+	if err := room.DeclineDraw("mallory"); err == nil {
+		t.Error("a user outside the room declined the draw offer")
+	}
+	if room.IsGameOver || room.drawOfferBy != "alice" {
+		t.Errorf("draw offer changed by a non-player: game over %v, offered by %q", room.IsGameOver, room.drawOfferBy)
+	}
+
+	if err := room.DeclineDraw("bob"); err != nil {
+		t.Errorf("opponent could not decline the offer: %v", err)
+	}
+}
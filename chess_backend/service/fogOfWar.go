@@ -0,0 +1,135 @@
+package service
+
+import (
+	"chess_backend/chess"
+)
+
+// Projection de l'état de la room pour chaque destinataire.
+// En Fog of War, un joueur ne reçoit que ce que ses pièces voient ; les autres
+// variantes partagent la même position entre tous les destinataires.
+
+func (room *ChessGameRoom) isFogOfWar() bool {
+	return room.Variant == VariantFogOfWar
+}
+
+// Indique si tous les destinataires voient la partie sans brouillard.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) seesEverything() bool {
+	return !room.isFogOfWar() || room.IsGameOver
+}
+
+// Position courante vue par username ; vide s'il ne joue pas dans la room.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) fenFor(username string) string {
+	if room.seesEverything() {
+		return room.PositionFEN
+	}
+	color, found := room.playerColor(username)
+	if !found {
+		return ""
+	}
+	view := room.game.Position().FogView(color)
+	return view.FEN()
+}
+
+// Historique des coups vu par username.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) movesFor(username string) []Move {
+	if room.seesEverything() {
+		return room.Moves
+	}
+	if _, found := room.playerColor(username); !found {
+		return []Move{}
+	}
+	moves := make([]Move, len(room.Moves))
+	for ply := range room.Moves {
+		moves[ply] = room.moveFor(username, ply)
+	}
+	return moves
+}
+
+// Coup d'indice ply vu par username : ses propres coups sont connus, ceux de l'adversaire
+// ne montrent que les cases de départ et d'arrivée que ses pièces voyaient.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) moveFor(username string, ply int) Move {
+	record := room.Moves[ply]
+	if room.seesEverything() {
+		return record
+	}
+	color, found := room.playerColor(username)
+	if !found {
+		return Move{Ply: record.Ply, Clock: record.Clock, Timestamp: record.Timestamp}
+	}
+
+	before, after := room.game.PositionAt(ply), room.game.PositionAt(ply+1)
+	view := after.FogView(color)
+	if before.Turn() == color {
+		record.FEN = view.FEN()
+		record.IsCheck = false
+		return record
+	}
+
+	move := room.game.Moves()[ply]
+	masked := Move{
+		Ply:       record.Ply,
+		FEN:       view.FEN(),
+		Clock:     record.Clock,
+		Timestamp: record.Timestamp,
+	}
+	fromSeen := before.Visible(color).Has(move.From)
+	toSeen := after.Visible(color).Has(move.To)
+	if fromSeen {
+		masked.From = record.From
+	}
+	if toSeen {
+		masked.To = record.To
+		masked.Piece = record.Piece
+		masked.Promotion = record.Promotion
+		masked.IsCapture = record.IsCapture
+	} else if captured := before.PieceAt(move.To); captured != chess.NoPiece && captured.Color() == color {
+		// Le joueur voit disparaître sa pièce, sans voir la pièce qui l'a prise
+		masked.To = record.To
+		masked.IsCapture = true
+	}
+	if fromSeen && toSeen {
+		masked.UCI = record.UCI
+		masked.SAN = record.SAN
+		masked.IsCastle = record.IsCastle
+	}
+	return masked
+}
+
+// Remplacer dans un état de partie la position et les coups par ce que voit username
+func (room *ChessGameRoom) projectState(state map[string]interface{}, username string) map[string]interface{} {
+	room.mutex.RLock()
+	defer room.mutex.RUnlock()
+
+	if room.seesEverything() {
+		return state
+	}
+	for _, key := range []string{"positonFen", "fen"} {
+		if _, exists := state[key]; exists {
+			state[key] = room.fenFor(username)
+		}
+	}
+	if _, exists := state["moves"]; exists {
+		state["moves"] = room.movesFor(username)
+	}
+	return state
+}
+
+// Envoyer à chaque connexion de la room un message construit pour son utilisateur
+func (room *ChessGameRoom) BroadcastProjected(build func(username string) WebSocketMessage) {
+	room.mutex.RLock()
+	connections := make(map[string]*SafeConn)
+	for username, conn := range room.Connections {
+		connections[username] = conn
+	}
+	room.mutex.RUnlock()
+
+	for username, conn := range connections {
+		if conn != nil {
+			conn.Send(build(username))
+		}
+	}
+}
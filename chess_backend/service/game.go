@@ -1,14 +1,14 @@
 package service
 
 import (
+	"chess_backend/chess"
+	"encoding/json"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 )
 
-var gameTime = 10
-
 type ChessGameRoom struct {
 	RoomID      string               `json:"room_id"`
 	WhitePlayer OnlineUser           `json:"white_player"`
@@ -23,21 +23,51 @@ type ChessGameRoom struct {
 	GameCreatorUID    string `json:"game_creator_uid"`
 	PositionFEN       string `json:"position_fen"`
 	WinnerID          string `json:"winner_id,omitempty"`
+	Result            string `json:"result,omitempty"`
+	Termination       string `json:"termination,omitempty"`
 	WhitesTime        string `json:"whites_time"`
 	BlacksTime        string `json:"blacks_time"`
 	IsWhitesTurn      bool   `json:"is_whites_turn"`
 	IsGameOver        bool   `json:"is_game_over"`
+	Rated             bool   `json:"rated"`
+	Variant           string `json:"variant"`
+	TimeControl       TimeControl `json:"time_control"`
+	Pockets           *Pockets `json:"pockets,omitempty"`
 	Moves             []Move `json:"moves"`
 	Timer             *ChessTimer
 	InvitationTimeout *InvitationTimeout
 	onlineManager     *OnlineUsersManager
+	game              *chess.Game
+	drawOfferBy       string
+	takebackRequestBy string
+	partner           *ChessGameRoom // échiquier lié en Bughouse
+	bot               *BotPlayer     // adversaire joué par le moteur intégré
 }
 
 
+// Réserves des deux camps en Crazyhouse, par lettre de pièce
+type Pockets struct {
+	White map[string]int `json:"white"`
+	Black map[string]int `json:"black"`
+}
+
+// Coup accepté par le serveur, avec tout ce qu'il faut pour rejouer la partie
 type Move struct {
-	From  string `json:"from"`
-	To    string `json:"to"`
-	Piece string `json:"piece"`
+	Ply       int       `json:"ply"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Piece     string    `json:"piece"`
+	UCI       string    `json:"uci"`
+	SAN       string    `json:"san"`
+	Promotion string    `json:"promotion,omitempty"`
+	Drop      bool      `json:"drop,omitempty"` // pièce parachutée depuis la réserve (From vide)
+	IsCapture bool      `json:"isCapture"`
+	IsCheck   bool      `json:"isCheck"`
+	IsCastle  bool      `json:"isCastle"`
+	FEN       string    `json:"fen"`
+	Clock     int       `json:"clock"` // secondes restantes du joueur après son coup (-1 si inconnues)
+	Comment   string    `json:"comment,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 type RoomStatus string
@@ -85,15 +115,39 @@ func (rm *RoomManager) CreateRoom(invitation InvitationMessage) *ChessGameRoom {
 		GameState:     make(map[string]interface{}),
 		RoomOrigin:    "invitation", // Marquer l'origine
 		mutex:         sync.RWMutex{},
-		PositionFEN:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
-		IsWhitesTurn:  true,
 		IsGameOver:    false,
+		Rated:         invitation.Rated,
 		Moves:         []Move{},
 		onlineManager: rm.onlineManager,
 	}
 
+	// Position de départ : FEN de l'invitation (déjà validée) ou position de la variante
+	room.Variant = invitation.Variant
+	if room.Variant == "" {
+		room.Variant = VariantStandard
+	}
+	game, err := newVariantGame(room.Variant, invitation.FEN)
+	if err != nil {
+		log.Printf("Invalid starting FEN for room %s, using initial position: %v", invitation.RoomID, err)
+		game, _ = newVariantGame(room.Variant, "")
+	}
+	room.game = game
+	room.PositionFEN = game.Position().FEN()
+	room.Pockets = pocketsOf(game.Position())
+	room.IsWhitesTurn = game.Position().Turn() == chess.White
+
+	// Cadence de l'invitation (déjà validée), 10 minutes par défaut
+	timeControl, err := ParseTimeControl(invitation.TimeControl)
+	if err != nil {
+		log.Printf("Invalid time control for room %s, using default: %v", invitation.RoomID, err)
+		timeControl = DefaultTimeControl
+	}
+	room.TimeControl = timeControl
+	room.WhitesTime = formatTime(timeControl.Base())
+	room.BlacksTime = formatTime(timeControl.Base())
+
 	// Créer et configurer le timer
-	timer := NewChessTimer(room, gameTime)
+	timer := NewChessTimer(room, timeControl)
 	room.Timer = timer
 
 	// Stocker la room
@@ -105,25 +159,45 @@ func (rm *RoomManager) CreateRoom(invitation InvitationMessage) *ChessGameRoom {
 	return room
 }
 
-func (room *ChessGameRoom) SendMove(moveData struct {
-	GameID       string      `json:"gameId"`
-	FromUserID   string      `json:"fromUserId"`
-	ToUserID     string      `json:"toUserId"`
-	ToUsername   string      `json:"toUsername"`
-	Move         interface{} `json:"move"`
-	FEN          string      `json:"fen"`
-	IsWhitesTurn bool        `json:"isWhitesTurn"`
-}) error {
+// Jouer le coup de username reçu à l'instant receivedAt, puis le transmettre à l'adversaire
+func (room *ChessGameRoom) SendMove(username string, moveData MoveData, receivedAt time.Time) error {
 	room.mutex.Lock()
 	defer room.mutex.Unlock()
 
-	if room.IsWhitesTurn != !moveData.IsWhitesTurn {
-		return fmt.Errorf("not your turn")
+	// Le serveur valide le coup et calcule lui-même la nouvelle position
+	if _, err := room.applyMove(username, moveData.Move, receivedAt); err != nil {
+		return err
+	}
+	record := room.Moves[len(room.Moves)-1]
+
+	// Bughouse : les joueurs de l'autre échiquier suivent ce coup
+	if room.partner != nil {
+		go room.partner.BroadcastMessage(room.partnerMoveMessage(record))
+	}
+
+	// Fin de partie (mat, pat, nulle) : la room se termine après l'envoi du coup
+	if outcome, over := room.gameOutcome(); over {
+		room.IsGameOver = true
+		go room.finishGame(outcome)
+	}
+
+	// Le tour a changé dans le timer lors de la validation : diffuser les pendules après le coup
+	if room.Timer != nil {
+		defer room.broadcastLocked(room.Timer.timeUpdateMessage())
+	}
+
+	// Le bot n'a pas de connexion : il répond depuis sa propre goroutine
+	if room.bot != nil && username != room.bot.user.Username {
+		if !room.IsGameOver {
+			go room.bot.play()
+		}
+		return nil
 	}
 
-	// Mettre à jour l'état de la partie
-	room.PositionFEN = moveData.FEN
-	room.IsWhitesTurn = !moveData.IsWhitesTurn
+	// Le destinataire est toujours l'adversaire, quel que soit le toUsername envoyé
+	if opponent, found := room.GetOtherPlayer(username); found {
+		moveData.ToUsername = opponent
+	}
 
 	// Vérifier et obtenir la connexion du destinataire de manière thread-safe
 	targetConn, exists := room.Connections[moveData.ToUsername]
@@ -145,8 +219,38 @@ func (room *ChessGameRoom) SendMove(moveData struct {
 		}
 	}
 
-	// Préparer le message avec l'ID de la room
-	moveMessage := WebSocketMessage{
+	// Préparer le message avec l'ID de la room, tel que le destinataire peut le voir
+	moveMessage := room.moveMessageFor(moveData.ToUsername, moveData, record)
+
+	// Fog of War : le joueur qui a joué reçoit aussi sa nouvelle vue de l'échiquier
+	if room.isFogOfWar() {
+		if conn, exists := room.Connections[username]; exists {
+			conn.Send(room.moveMessageFor(username, moveData, record))
+		}
+	}
+
+	// Mettre le mouvement en file : l'écriture se fait hors du verrou de la room
+	if err := targetConn.Send(moveMessage); err != nil {
+		log.Printf("Failed to send move to %s in room %s: %v", moveData.ToUsername, room.RoomID, err)
+		return fmt.Errorf("failed to send move: %v", err)
+	}
+
+	return nil
+}
+
+
+
+// Message game_move destiné à username ; en Fog of War le coup et la position sont masqués.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) moveMessageFor(username string, moveData MoveData, record Move) WebSocketMessage {
+	move := moveData.Move
+	var visible *Move
+	if room.isFogOfWar() {
+		masked := room.moveFor(username, len(room.Moves)-1)
+		record, visible, move = masked, &masked, nil
+	}
+
+	return WebSocketMessage{
 		Type: "game_move",
 		Content: string(mustJson(struct {
 			GameID       string      `json:"gameId"`
@@ -154,6 +258,11 @@ func (room *ChessGameRoom) SendMove(moveData struct {
 			ToUserID     string      `json:"toUserId"`
 			ToUsername   string      `json:"toUsername"`
 			Move         interface{} `json:"move"`
+			Record       *Move       `json:"record,omitempty"`
+			UCI          string      `json:"uci"`
+			SAN          string      `json:"san"`
+			Drop         bool        `json:"drop,omitempty"`
+			Pockets      *Pockets    `json:"pockets,omitempty"`
 			FEN          string      `json:"fen"`
 			IsWhitesTurn bool        `json:"isWhitesTurn"`
 			RoomOrigin   string      `json:"roomOrigin"`
@@ -162,40 +271,19 @@ func (room *ChessGameRoom) SendMove(moveData struct {
 			FromUserID:   moveData.FromUserID,
 			ToUserID:     moveData.ToUserID,
 			ToUsername:   moveData.ToUsername,
-			Move:         moveData.Move,
-			FEN:          moveData.FEN,
-			IsWhitesTurn: moveData.IsWhitesTurn,
+			Move:         move,
+			Record:       visible,
+			UCI:          record.UCI,
+			SAN:          record.SAN,
+			Drop:         record.Drop,
+			Pockets:      room.Pockets,
+			FEN:          room.fenFor(username),
+			IsWhitesTurn: room.IsWhitesTurn,
 			RoomOrigin:   room.RoomOrigin,
 		})),
 	}
-
-	// Envoyer le mouvement avec retry et logging
-	maxRetries := 3
-
-	for i := 0; i < maxRetries; i++ {
-		err := targetConn.WriteJSON(moveMessage)
-		if err == nil {
-			break
-		}
-
-		if i == maxRetries-1 {
-			log.Printf("Failed to send move to %s in room %s after %d attempts: %v",
-				moveData.ToUsername, room.RoomID, maxRetries, err)
-			return fmt.Errorf("failed to send move after %d retries: %v", maxRetries, err)
-		}
-		time.Sleep(100 * time.Millisecond)
-	}
-
-	// Changer le tour dans le timer
-	if room.Timer != nil {
-		go room.Timer.SwitchTurn()
-	}
-
-	return nil
 }
 
-
-
 func (rm *RoomManager) GetRoom(roomID string) (*ChessGameRoom, bool) {
 	rm.mutex.RLock()
 	defer rm.mutex.RUnlock()
@@ -214,6 +302,9 @@ func (rm *RoomManager) RemoveRoom(roomID string) {
 			room.Timer.Stop()
 		}
 
+		// Conserver la partie si elle a un résultat
+		rm.archiveRoom(room)
+
 		// Nettoyer les connexions de la room
 		room.mutex.Lock()
 		for username := range room.Connections {
@@ -239,6 +330,9 @@ func (rm *RoomManager) RemoveSpecificRoom(roomID string) {
         room.Timer.Stop()
     }
 
+    // Conserver la partie si elle a un résultat
+    rm.archiveRoom(room)
+
     // Nettoyer les connexions de la room
     room.mutex.Lock()
     for username := range room.Connections {
@@ -311,6 +405,20 @@ func (m *OnlineUsersManager) RemoveUserFromRoom(username string) ([]OnlineUser,
 		return nil, fmt.Errorf("could not find other player in room")
 	}
 
+	// Leaving mid-game is scored as a loss, announced to the opponent
+	if roomToRemove.Timer != nil {
+		roomToRemove.Timer.Stop()
+	}
+	if outcome, forfeited := roomToRemove.forfeit(username); forfeited {
+		roomToRemove.mutex.RLock()
+		gameOver := roomToRemove.gameOverMessage(outcome)
+		opponentConn, connected := roomToRemove.Connections[otherUsername]
+		roomToRemove.mutex.RUnlock()
+		if connected {
+			opponentConn.Send(gameOver)
+		}
+	}
+
 	// Remove the room
 	m.roomManager.RemoveRoom(roomToRemove.RoomID)
 
@@ -324,6 +432,40 @@ func (m *OnlineUsersManager) RemoveUserFromRoom(username string) ([]OnlineUser,
 	return m.getCurrentOnlineUsers(), nil
 }
 
+// Traiter une action d'un joueur sur la partie gameId du message (nulle, abandon, reprise) ;
+// seuls les joueurs de la room peuvent agir, un refus leur est renvoyé dans un message errorType
+func (m *OnlineUsersManager) handleRoomAction(username string, msg WebSocketMessage, errorType string, action func(room *ChessGameRoom) error) {
+	var actionData struct {
+		GameID string `json:"gameId"`
+	}
+	if err := json.Unmarshal([]byte(msg.Content), &actionData); err != nil {
+		log.Printf("Error parsing %s message: %v", msg.Type, err)
+		return
+	}
+
+	room, exists := m.roomManager.GetRoom(actionData.GameID)
+	if !exists {
+		log.Printf("Room not found: %s", actionData.GameID)
+		return
+	}
+
+	// Les joueurs d'une room ne changent pas : pas besoin de son verrou
+	err := fmt.Errorf("user %s not in room %s", username, room.RoomID)
+	if _, found := room.playerColor(username); found {
+		err = action(room)
+	}
+	if err != nil {
+		log.Printf("Error handling %s from %s: %v", msg.Type, username, err)
+		m.sendToUser(username, WebSocketMessage{
+			Type: errorType,
+			Content: string(mustJson(map[string]string{
+				"gameId": actionData.GameID,
+				"error":  err.Error(),
+			})),
+		})
+	}
+}
+
 func (room *ChessGameRoom) BroadcastMessage(message WebSocketMessage) {
 	room.mutex.RLock()
 	connections := make(map[string]*SafeConn)
@@ -332,12 +474,156 @@ func (room *ChessGameRoom) BroadcastMessage(message WebSocketMessage) {
 	}
 	room.mutex.RUnlock()
 
+	// Chaque connexion écrit sa file dans l'ordre : pas de goroutine par envoi
 	for _, conn := range connections {
 		if conn != nil {
-			go func(c *SafeConn) {
-				c.WriteJSON(message)
-			}(conn)
+			conn.Send(message)
+		}
+	}
+}
+
+// Diffuser un message à toutes les connexions de la room. Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) broadcastLocked(message WebSocketMessage) {
+	for _, conn := range room.Connections {
+		if conn != nil {
+			conn.Send(message)
 		}
 	}
 }
 
+// Terminer la partie : arrêter le timer, annoncer le résultat et nettoyer la room
+func (room *ChessGameRoom) finishGame(outcome chess.Outcome) {
+	if room.Timer != nil {
+		room.Timer.Stop()
+	}
+
+	room.mutex.Lock()
+	if room.Status == RoomStatusFinished {
+		room.mutex.Unlock()
+		return
+	}
+	roomID := room.RoomID
+	whiteUsername := room.WhitePlayer.Username
+	blackUsername := room.BlackPlayer.Username
+
+	// Marquer la partie comme terminée
+	room.recordOutcome(outcome)
+
+	// Copier les connexions nécessaires
+	connections := make(map[string]*SafeConn)
+	for username, conn := range room.Connections {
+		connections[username] = conn
+	}
+	gameOver := room.gameOverMessage(outcome)
+	room.mutex.Unlock()
+
+	// Envoyer aux deux joueurs
+	for _, conn := range connections {
+		conn.Send(gameOver)
+	}
+
+	// Nettoyer la room après un court délai
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		if room.onlineManager != nil {
+			// Nettoyer uniquement les joueurs de cette room
+			room.onlineManager.cleanupPlayerFromPublicQueue(whiteUsername)
+			room.onlineManager.cleanupPlayerFromPublicQueue(blackUsername)
+
+			// Mettre à jour le statut des joueurs
+			room.onlineManager.userStore.UpdateUserRoomStatus(whiteUsername, false)
+			room.onlineManager.userStore.UpdateUserRoomStatus(blackUsername, false)
+
+			// Supprimer uniquement cette room
+			room.onlineManager.roomManager.RemoveSpecificRoom(roomID)
+
+			room.onlineManager.broadcastOnlineUsers()
+		}
+	}()
+}
+
+// Message game_over annonçant le résultat enregistré dans la room.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) gameOverMessage(outcome chess.Outcome) WebSocketMessage {
+	var whiteTime, blackTime time.Duration
+	if room.Timer != nil {
+		whiteTime, blackTime = room.Timer.Remaining()
+	}
+
+	gameOver := map[string]interface{}{
+		"gameId":    room.RoomID,
+		"winner":    outcomeWinner(outcome),
+		"reason":    string(outcome.Termination),
+		"result":    outcome.Result(),
+		"whiteTime": formatTime(int(whiteTime / time.Second)),
+		"blackTime": formatTime(int(blackTime / time.Second)),
+		"whiteMs":   whiteTime.Milliseconds(),
+		"blackMs":   blackTime.Milliseconds(),
+		"winnerId":  room.WinnerID,
+	}
+	// Fog of War : le brouillard se lève, la position et les coups sont révélés
+	if room.isFogOfWar() {
+		gameOver["fen"] = room.PositionFEN
+		gameOver["moves"] = room.Moves
+	}
+
+	return WebSocketMessage{
+		Type:    "game_over",
+		Content: string(mustJson(gameOver)),
+	}
+}
+
+// Enregistrer le résultat dans la room et retourner le vainqueur ("white", "black" ou "draw").
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) recordOutcome(outcome chess.Outcome) string {
+	room.IsGameOver = true
+	room.Status = RoomStatusFinished
+	room.Result = outcome.Result()
+	room.Termination = string(outcome.Termination)
+
+	// Bughouse : la fin d'un échiquier termine aussi l'autre
+	if room.partner != nil {
+		go room.partner.finishGame(partnerOutcome(outcome))
+	}
+
+	winner := outcomeWinner(outcome)
+	switch winner {
+	case "white":
+		room.WinnerID = room.WhitePlayer.ID
+	case "black":
+		room.WinnerID = room.BlackPlayer.ID
+	default:
+		room.WinnerID = ""
+	}
+	return winner
+}
+
+// Couleur jouée par un joueur de la room
+func (room *ChessGameRoom) playerColor(username string) (chess.Color, bool) {
+	switch username {
+	case room.WhitePlayer.Username:
+		return chess.White, true
+	case room.BlackPlayer.Username:
+		return chess.Black, true
+	}
+	return chess.White, false
+}
+
+// Fin de partie selon les règles ; les nulles réclamables sont appliquées d'office.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) gameOutcome() (chess.Outcome, bool) {
+	if outcome, over := room.game.Outcome(); over {
+		return outcome, true
+	}
+	return room.game.ClaimableDraw()
+}
+
+func outcomeWinner(outcome chess.Outcome) string {
+	switch {
+	case outcome.Draw:
+		return "draw"
+	case outcome.Winner == chess.White:
+		return "white"
+	}
+	return "black"
+}
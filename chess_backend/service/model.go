@@ -1,6 +1,9 @@
 package service
 
 import (
+	"chess_backend/uci"
+	"errors"
+	"log"
 	"sync"
 	"time"
 
@@ -35,9 +38,15 @@ type OnlineUsersManager struct {
 	mutex       sync.RWMutex
 	connections map[string]*SafeConn
 	userStore   *UserStore
+	gameArchive *GameArchive
 	roomManager *RoomManager
 	tempRoomManager    *TemporaryRoomManager
 	publicQueue *PublicGameQueue
+	bughouse    *BughouseManager
+	uciPool     *uci.Pool
+	analyzer    *Analyzer
+	reviewer    *Reviewer
+	lagMonitor  *LagMonitor
 }
 
 type PublicGameQueue struct {
@@ -51,18 +60,82 @@ type QueuedPlayer struct {
 	JoinedAt   time.Time
 	Timer      *time.Timer
 	Connection *SafeConn
+	Variant    string
+	TimeControl TimeControl // cadence demandée : seuls les joueurs de même cadence sont appariés
 }
 
+// Nombre de messages en attente d'envoi au-delà duquel un client est jugé trop lent
+const sendQueueSize = 64
+
+var (
+	errConnClosed    = errors.New("connection closed")
+	errSendQueueFull = errors.New("send queue full")
+	errUpdateDropped = errors.New("clock update dropped")
+)
+
 type SafeConn struct {
-	conn  *websocket.Conn
-	mutex sync.Mutex
+	conn      *websocket.Conn
+	mutex     sync.Mutex
+	queue     chan interface{} // tous les messages sortants, écrits dans l'ordre par une seule goroutine
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 func NewSafeConn(conn *websocket.Conn) *SafeConn {
-	return &SafeConn{conn: conn}
+	sc := &SafeConn{
+		conn:  conn,
+		queue: make(chan interface{}, sendQueueSize),
+		done:  make(chan struct{}),
+	}
+	go sc.writeLoop()
+	return sc
+}
+
+// Mettre un message en file sans attendre l'écriture. File pleine : une mise à jour des pendules
+// est abandonnée (la suivante la remplace), tout autre message ferme la connexion du client trop lent.
+func (sc *SafeConn) Send(v interface{}) error {
+	select {
+	case <-sc.done:
+		return errConnClosed
+	default:
+	}
+	select {
+	case sc.queue <- v:
+		return nil
+	default:
+	}
+
+	if message, ok := v.(WebSocketMessage); ok && message.Type == "time_update" {
+		return errUpdateDropped
+	}
+	log.Printf("Send queue full for %s, closing connection", sc.conn.RemoteAddr())
+	sc.Close()
+	sc.conn.Close()
+	return errSendQueueFull
+}
+
+func (sc *SafeConn) writeLoop() {
+	for {
+		select {
+		case v := <-sc.queue:
+			if err := sc.write(v); err != nil {
+				sc.Close()
+				return
+			}
+		case <-sc.done:
+			return
+		}
+	}
+}
+
+// Arrêter l'envoi des messages en file (la connexion elle-même est fermée par la boucle de lecture)
+func (sc *SafeConn) Close() {
+	sc.closeOnce.Do(func() {
+		close(sc.done)
+	})
 }
 
-func (sc *SafeConn) WriteJSON(v interface{}) error {
+func (sc *SafeConn) write(v interface{}) error {
 	sc.mutex.Lock()
 	defer sc.mutex.Unlock()
 	return sc.conn.WriteJSON(v)
@@ -91,4 +164,9 @@ type InvitationMessage struct {
 	ToUserID     string                `json:"to_user_id"`
 	ToUsername   string                `json:"to_username"`
 	RoomID       string                `json:"room_id,omitempty"`
+	Rated        bool                  `json:"rated,omitempty"`
+	FEN          string                `json:"fen,omitempty"` // position de départ personnalisée
+	Variant      string                `json:"variant,omitempty"`
+	BotLevel     int                   `json:"bot_level,omitempty"` // force de l'ordinateur (1 à 8)
+	TimeControl  string                `json:"time_control,omitempty"` // cadence "minutes+incrément", 10+0 par défaut
 }
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Codes d'erreur renvoyés dans le message move_error
+const (
+	MoveErrorInvalidPayload = "invalid_payload"
+	MoveErrorNotYourTurn    = "not_your_turn"
+	MoveErrorNotInRoom      = "not_in_room"
+	MoveErrorIllegalMove    = "illegal_move"
+	MoveErrorGameOver       = "game_over"
+)
+
+// Erreur structurée envoyée au joueur lorsqu'un coup est refusé.
+// FEN et IsWhitesTurn permettent au client de se resynchroniser.
+type MoveError struct {
+	GameID       string `json:"gameId"`
+	Code         string `json:"code"`
+	Message      string `json:"error"`
+	FEN          string `json:"fen"`
+	IsWhitesTurn bool   `json:"isWhitesTurn"`
+}
+
+func (e *MoveError) Error() string {
+	return e.Message
+}
+
+// Contenu d'un message game_move envoyé par le client
+type MoveData struct {
+	GameID       string      `json:"gameId"`
+	FromUserID   string      `json:"fromUserId"`
+	ToUserID     string      `json:"toUserId"`
+	ToUsername   string      `json:"toUsername"`
+	Move         interface{} `json:"move"`
+	FEN          string      `json:"fen"`
+	IsWhitesTurn bool        `json:"isWhitesTurn"`
+}
+
+// Coup décrit par le client : déplacement, ou parachutage en Crazyhouse
+type clientMove struct {
+	from, to  chess.Square
+	promotion chess.PieceType
+	drop      chess.PieceType
+}
+
+// Extraire le coup du champ "move" du client.
+// Formats acceptés : "e2e4", "N@f3", {"from":"e2","to":"e4","promo":"q"},
+// {"drop":"n","to":"f3"} ou des index de cases 0-63 (0 = a8, convention du plateau Flutter).
+func parseClientMove(raw interface{}) (clientMove, error) {
+	var move clientMove
+	var err error
+
+	switch data := raw.(type) {
+	case string:
+		if len(data) == 4 && data[1] == '@' {
+			if move.drop, err = parseDropPiece(data[:1]); err != nil {
+				return move, err
+			}
+			move.to, err = parseClientSquare(data[2:4])
+			return move, err
+		}
+		if len(data) < 4 || len(data) > 5 {
+			return move, fmt.Errorf("invalid move %q", data)
+		}
+		if move.from, err = parseClientSquare(data[0:2]); err != nil {
+			return move, err
+		}
+		if move.to, err = parseClientSquare(data[2:4]); err != nil {
+			return move, err
+		}
+		move.promotion, err = parsePromotion(data[4:])
+		return move, err
+
+	case map[string]interface{}:
+		if move.to, err = parseClientSquare(data["to"]); err != nil {
+			return move, err
+		}
+		if drop, _ := data["drop"].(string); drop != "" {
+			move.drop, err = parseDropPiece(drop)
+			return move, err
+		}
+		if move.from, err = parseClientSquare(data["from"]); err != nil {
+			return move, err
+		}
+		promo, _ := data["promo"].(string)
+		if promo == "" {
+			promo, _ = data["promotion"].(string)
+		}
+		move.promotion, err = parsePromotion(promo)
+		return move, err
+	}
+	return move, fmt.Errorf("missing move")
+}
+
+func parseClientSquare(raw interface{}) (chess.Square, error) {
+	switch sq := raw.(type) {
+	case string:
+		return chess.ParseSquare(strings.ToLower(sq))
+	case float64:
+		index := int(sq)
+		if float64(index) != sq || index < 0 || index > 63 {
+			return chess.NoSquare, fmt.Errorf("invalid square index %v", sq)
+		}
+		return chess.NewSquare(index%8, 7-index/8), nil
+	}
+	return chess.NoSquare, fmt.Errorf("invalid square %v", raw)
+}
+
+func parsePromotion(promo string) (chess.PieceType, error) {
+	switch strings.ToLower(promo) {
+	case "":
+		return chess.NoPieceType, nil
+	case "q", "queen":
+		return chess.Queen, nil
+	case "r", "rook":
+		return chess.Rook, nil
+	case "b", "bishop":
+		return chess.Bishop, nil
+	case "n", "knight":
+		return chess.Knight, nil
+	}
+	return chess.NoPieceType, fmt.Errorf("invalid promotion %q", promo)
+}
+
+// Pièce parachutée : pion ou toute pièce de promotion
+func parseDropPiece(piece string) (chess.PieceType, error) {
+	switch strings.ToLower(piece) {
+	case "p", "pawn":
+		return chess.Pawn, nil
+	case "":
+		return chess.NoPieceType, fmt.Errorf("missing drop piece")
+	}
+	pt, err := parsePromotion(piece)
+	if err != nil {
+		return chess.NoPieceType, fmt.Errorf("invalid drop piece %q", piece)
+	}
+	return pt, nil
+}
+
+// Valider le coup d'un joueur, reçu à l'instant receivedAt, contre la position de la room et l'appliquer.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) applyMove(username string, raw interface{}, receivedAt time.Time) (chess.Move, error) {
+	if room.IsGameOver || room.Status == RoomStatusFinished {
+		return chess.NullMove, room.moveError(username, MoveErrorGameOver, "game is over")
+	}
+
+	color, found := room.playerColor(username)
+	if !found {
+		return chess.NullMove, room.moveError(username, MoveErrorNotInRoom, "player not in this room")
+	}
+
+	if color != room.game.Position().Turn() {
+		return chess.NullMove, room.moveError(username, MoveErrorNotYourTurn, "not your turn")
+	}
+
+	// Le coup compte à sa réception, pas à la prise du verrou : arrivé après la chute du drapeau, il est refusé
+	lag := room.playerLag(username)
+	if room.Timer != nil && room.Timer.HasFlagged(color, receivedAt, lag) {
+		go room.Timer.handleTimeOut(color)
+		return chess.NullMove, room.moveError(username, MoveErrorGameOver, "time is up")
+	}
+
+	clientMove, err := parseClientMove(raw)
+	if err != nil {
+		return chess.NullMove, room.moveError(username, MoveErrorInvalidPayload, err.Error())
+	}
+
+	var move chess.Move
+	if clientMove.drop != chess.NoPieceType {
+		move, err = room.game.Position().FindDrop(clientMove.drop, clientMove.to)
+	} else {
+		move, err = room.game.Position().FindMove(clientMove.from, clientMove.to, clientMove.promotion)
+	}
+	if err != nil {
+		if errors.Is(err, chess.ErrIllegalMove) {
+			return chess.NullMove, room.moveError(username, MoveErrorIllegalMove, err.Error())
+		}
+		return chess.NullMove, room.moveError(username, MoveErrorInvalidPayload, err.Error())
+	}
+
+	record := newMoveRecord(room.game.Position(), move, len(room.Moves)+1)
+	record.Timestamp = receivedAt
+
+	// Bughouse : la pièce prise rejoint la réserve du partenaire, qui joue l'autre couleur
+	if room.partner != nil {
+		if captured := room.game.Position().CapturedPiece(move); captured != chess.NoPieceType {
+			go room.partner.receivePiece(color.Other(), captured)
+		}
+	}
+
+	room.game.Play(move)
+	room.expireDrawOffer(username)
+	room.expireTakebackRequest()
+	room.PositionFEN = room.game.Position().FEN()
+	room.IsWhitesTurn = room.game.Position().Turn() == chess.White
+	room.Pockets = pocketsOf(room.game.Position())
+
+	// Décompter le temps du coup à l'instant de sa réception
+	if room.Timer != nil {
+		record.Clock = int(room.Timer.SwitchTurn(receivedAt, lag) / time.Second)
+	}
+	room.Moves = append(room.Moves, record)
+
+	return move, nil
+}
+
+// Latence estimée de la connexion d'un joueur, pour la compensation des pendules
+func (room *ChessGameRoom) playerLag(username string) time.Duration {
+	if room.onlineManager == nil || room.onlineManager.lagMonitor == nil {
+		return 0
+	}
+	return room.onlineManager.lagMonitor.Lag(username)
+}
+
+// Enregistrement d'un coup légal joué depuis la position before (sans pendule ni horodatage)
+func newMoveRecord(before *chess.Position, move chess.Move, ply int) Move {
+	after := before.Play(move)
+	record := Move{
+		Ply:       ply,
+		To:        move.To.String(),
+		UCI:       before.UCI(move),
+		SAN:       before.SAN(move),
+		IsCapture: move.IsCapture(),
+		IsCheck:   after.InCheck(),
+		IsCastle:  move.IsCastle(),
+		FEN:       after.FEN(),
+	}
+	if move.Promotion != chess.NoPieceType {
+		record.Promotion = string(move.Promotion.Letter())
+	}
+	if move.IsDrop() {
+		record.Drop = true
+		record.Piece = string(chess.NewPiece(before.Turn(), move.Drop).Letter())
+	} else {
+		record.From = move.From.String()
+		record.Piece = string(before.PieceAt(move.From).Letter())
+	}
+	return record
+}
+
+// Réserves des deux camps en Crazyhouse (nil dans les autres variantes)
+func pocketsOf(position *chess.Position) *Pockets {
+	if !chess.HasPockets(position.Variant()) {
+		return nil
+	}
+	pockets := &Pockets{White: map[string]int{}, Black: map[string]int{}}
+	for _, pt := range []chess.PieceType{chess.Pawn, chess.Knight, chess.Bishop, chess.Rook, chess.Queen} {
+		letter := string(pt.Letter())
+		if n := position.Pocket(chess.White, pt); n > 0 {
+			pockets.White[letter] = n
+		}
+		if n := position.Pocket(chess.Black, pt); n > 0 {
+			pockets.Black[letter] = n
+		}
+	}
+	return pockets
+}
+
+// Erreur renvoyée à username, avec la position telle qu'il peut la voir (masquée en Fog of War).
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) moveError(username, code, message string) *MoveError {
+	return &MoveError{
+		GameID:       room.RoomID,
+		Code:         code,
+		Message:      message,
+		FEN:          room.fenFor(username),
+		IsWhitesTurn: room.IsWhitesTurn,
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Rendre une partie au format PGN
+func (game *ArchivedGame) PGN() string {
+	event := "Casual game"
+	if game.Rated {
+		event = "Rated game"
+	}
+
+	pgn := &chess.PGNGame{Result: game.Result}
+	pgn.SetTag("Event", event)
+	pgn.SetTag("Site", Getenv("PGN_SITE", "chess"))
+	pgn.SetTag("Date", "????.??.??")
+	if !game.StartedAt.IsZero() {
+		pgn.SetTag("Date", game.StartedAt.Format("2006.01.02"))
+	}
+	pgn.SetTag("Round", "-")
+	pgn.SetTag("White", game.WhitePlayer.Username)
+	pgn.SetTag("Black", game.BlackPlayer.Username)
+	pgn.SetTag("Result", game.Result)
+	pgn.SetTag("TimeControl", "-")
+	if game.TimeControl != "" {
+		pgn.SetTag("TimeControl", game.TimeControl)
+	}
+	if game.Termination != "" || game.Result == chess.ResultOngoing {
+		pgn.SetTag("Termination", chess.Termination(game.Termination).PGNTermination())
+	}
+
+	if name := pgnVariantName(game.Variant); name != "" {
+		pgn.SetTag("Variant", name)
+	}
+	if (game.StartFEN != "" && game.StartFEN != variantRules(game.Variant).StartingFEN()) || game.Variant == VariantChess960 {
+		pgn.SetTag("SetUp", "1")
+		pgn.SetTag("FEN", game.StartFEN)
+	}
+
+	// En-têtes d'origine d'une partie importée
+	names := make([]string, 0, len(game.Tags))
+	for name := range game.Tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pgn.SetTag(name, game.Tags[name])
+	}
+	pgn.SetTag("Result", game.Result)
+
+	for _, move := range game.Moves {
+		comment := move.Comment
+		if move.Clock >= 0 {
+			comment = strings.TrimSpace(chess.ClockComment(move.Clock) + " " + comment)
+		}
+		pgn.Moves = append(pgn.Moves, chess.PGNMove{
+			SAN:     move.SAN,
+			Comment: comment,
+		})
+	}
+
+	return pgn.String()
+}
+
+// Export PGN d'une partie en cours ou archivée
+func GamePGNHandler(gameArchive *GameArchive, onlineUsersManager *OnlineUsersManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID := mux.Vars(r)["id"]
+
+		var game *ArchivedGame
+		if room, exists := onlineUsersManager.roomManager.GetRoom(gameID); exists {
+			room.mutex.RLock()
+			hidden := !room.seesEverything()
+			room.mutex.RUnlock()
+			if hidden {
+				http.Error(w, "Fog of war game in progress", http.StatusForbidden)
+				return
+			}
+			snapshot := room.snapshot()
+			game = &snapshot
+		} else {
+			archived, err := gameArchive.GetGame(gameID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			game = archived
+		}
+
+		w.Header().Set("Content-Type", "application/x-chess-pgn")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", gameID+".pgn"))
+		w.Write([]byte(game.PGN()))
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Taille maximale d'un fichier PGN importé
+const maxPGNImportSize = 10 << 20
+
+type PGNImportError struct {
+	Index int    `json:"index"` // position de la partie dans le fichier, à partir de 0
+	Error string `json:"error"`
+}
+
+type PGNImportResult struct {
+	Imported int              `json:"imported"`
+	Games    []string         `json:"games"`
+	Errors   []PGNImportError `json:"errors"`
+}
+
+// Construire une partie archivée à partir d'une partie PGN validée par le moteur de règles
+func importPGNGame(text string, importedBy string, userStore *UserStore) (ArchivedGame, error) {
+	pgn, err := chess.ParsePGNGame(text)
+	if err != nil {
+		return ArchivedGame{}, err
+	}
+	if len(pgn.Moves) == 0 && len(pgn.Tags) == 0 {
+		return ArchivedGame{}, fmt.Errorf("empty game")
+	}
+
+	game, err := pgn.Play()
+	if err != nil {
+		return ArchivedGame{}, err
+	}
+
+	archived := ArchivedGame{
+		ID:          GenerateUniqueID(),
+		WhitePlayer: importedPlayer(pgn.Tag("White"), userStore),
+		BlackPlayer: importedPlayer(pgn.Tag("Black"), userStore),
+		RoomOrigin:  "import",
+		ImportedBy:  importedBy,
+		Variant:     VariantStandard,
+		StartFEN:    game.StartPosition().FEN(),
+		Result:      pgn.Result,
+		TimeControl: pgn.Tag("TimeControl"),
+		Tags:        make(map[string]string),
+		EndedAt:     time.Now(),
+	}
+
+	for _, tag := range pgn.Tags {
+		archived.Tags[tag.Name] = tag.Value
+	}
+	if rules, err := pgn.Variant(); err == nil {
+		archived.Variant = rules.Name()
+	}
+	if pgn.IsChess960() {
+		archived.Variant = VariantChess960
+	}
+	if date, err := time.Parse("2006.01.02", pgn.Tag("Date")); err == nil {
+		archived.StartedAt = date
+	}
+
+	// Rejouer la ligne principale pour obtenir le SAN canonique, les FEN et les pendules
+	position := *game.StartPosition()
+	for i, move := range game.Moves() {
+		record := newMoveRecord(&position, move, i+1)
+		record.Clock, record.Comment = chess.ParseClockComment(pgn.Moves[i].Comment)
+		archived.Moves = append(archived.Moves, record)
+		position = position.Play(move)
+	}
+
+	if outcome, over := game.Outcome(); over {
+		archived.Termination = string(outcome.Termination)
+		if outcome.Result() != archived.Result && archived.Result != chess.ResultOngoing {
+			return ArchivedGame{}, fmt.Errorf("result %s contradicts final position (%s)", archived.Result, outcome.Result())
+		}
+		archived.Result = outcome.Result()
+	}
+	archived.WinnerID = importedWinnerID(archived)
+
+	return archived, nil
+}
+
+// Lier un joueur PGN à un profil existant par son nom d'utilisateur
+func importedPlayer(name string, userStore *UserStore) OnlineUser {
+	player := OnlineUser{Username: name}
+	if user, err := userStore.GetUser(name); err == nil {
+		player.ID = user.ID
+	}
+	return player
+}
+
+func importedWinnerID(game ArchivedGame) string {
+	switch game.Result {
+	case chess.ResultWhiteWins:
+		return game.WhitePlayer.ID
+	case chess.ResultBlackWins:
+		return game.BlackPlayer.ID
+	}
+	return ""
+}
+
+// Import d'un fichier PGN multi-parties dans l'archive.
+// Une partie invalide est signalée sans interrompre l'import des suivantes.
+func GameImportHandler(gameArchive *GameArchive, userStore *UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		username := r.URL.Query().Get("username")
+		if username == "" {
+			http.Error(w, "Username is required", http.StatusUnauthorized)
+			return
+		}
+		if _, err := userStore.GetUser(username); err != nil {
+			http.Error(w, "Unknown user", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxPGNImportSize)
+
+		var reader io.Reader = r.Body
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Missing PGN file: %v", err), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			reader = file
+		}
+
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read PGN: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		result := PGNImportResult{Games: []string{}, Errors: []PGNImportError{}}
+		var games []ArchivedGame
+		for i, text := range chess.SplitPGN(string(data)) {
+			game, err := importPGNGame(text, username, userStore)
+			if err != nil {
+				result.Errors = append(result.Errors, PGNImportError{Index: i, Error: err.Error()})
+				continue
+			}
+			games = append(games, game)
+			result.Games = append(result.Games, game.ID)
+		}
+
+		if len(games) > 0 {
+			if err := gameArchive.AddGames(games); err != nil {
+				log.Printf("Error saving imported games: %v", err)
+				http.Error(w, "Failed to save imported games", http.StatusInternalServerError)
+				return
+			}
+		}
+		result.Imported = len(games)
+		log.Printf("User %s imported %d games (%d errors)", username, result.Imported, len(result.Errors))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
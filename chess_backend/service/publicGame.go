@@ -12,10 +12,10 @@ const (
 	PublicQueueLeave  string = "public_queue_leave"
 )
 
-func (m *OnlineUsersManager) handlePublicGameRequest(username string, userID string, conn *SafeConn) {
+func (m *OnlineUsersManager) handlePublicGameRequest(username string, userID string, variant string, timeControl TimeControl, conn *SafeConn) {
 	// Vérifier si le joueur est déjà dans une partie
 	if user, err := m.userStore.GetUser(username); err == nil && user.IsInRoom {
-		conn.WriteJSON(WebSocketMessage{
+		conn.Send(WebSocketMessage{
 			Type: "error",
 			Content: string(mustJson(map[string]string{
 				"message": "Vous êtes déjà dans une partie",
@@ -32,10 +32,13 @@ func (m *OnlineUsersManager) handlePublicGameRequest(username string, userID str
 		return
 	}
 
-	// Chercher l'adversaire qui attend depuis le plus longtemps
+	// Chercher l'adversaire qui attend depuis le plus longtemps pour la même variante et la même cadence
 	var opponent *QueuedPlayer
 	var longestWait time.Duration
 	for _, player := range m.publicQueue.waitingPlayers {
+		if player.Variant != variant || player.TimeControl.String() != timeControl.String() {
+			continue
+		}
 		waitTime := time.Since(player.JoinedAt)
 		if opponent == nil || waitTime > longestWait {
 			opponent = player
@@ -47,11 +50,13 @@ func (m *OnlineUsersManager) handlePublicGameRequest(username string, userID str
 		// Aucun adversaire disponible, ajouter le joueur à la file d'attente
 		timer := time.NewTimer(60 * time.Second)
 		queuedPlayer := &QueuedPlayer{
-			UserID:     userID,
-			Username:   username,
-			JoinedAt:   time.Now(),
-			Connection: conn,
-			Timer:      timer,
+			UserID:      userID,
+			Username:    username,
+			JoinedAt:    time.Now(),
+			Connection:  conn,
+			Timer:       timer,
+			Variant:     variant,
+			TimeControl: timeControl,
 		}
 
 		m.publicQueue.waitingPlayers[username] = queuedPlayer
@@ -78,6 +83,8 @@ func (m *OnlineUsersManager) handlePublicGameRequest(username string, userID str
 			ToUserID:     userID, // Noir
 			ToUsername:   username,
 			RoomID:       GenerateUniqueID(),
+			Variant:      variant,
+			TimeControl:  timeControl.String(),
 		}
 
 		// Créer la room et démarrer la partie
@@ -98,12 +105,15 @@ func (m *OnlineUsersManager) handlePublicGameRequest(username string, userID str
 			"isGameOver":     false,
 			"moves":          []Move{},
 			"winnerId":       "",
+			"variant":        room.Variant,
+			"timeControl":    room.TimeControl,
+			"clock":          room.clockState(),
 		}
 
 		var player1GameState, player2GameState map[string]interface{}
 
-		player1GameState = copyAndAddUserInfo(baseGameState, opponent.UserID, username)
-		player2GameState = copyAndAddUserInfo(baseGameState, userID, opponent.Username)
+		player1GameState = room.projectState(copyAndAddUserInfo(baseGameState, opponent.UserID, username), opponent.Username)
+		player2GameState = room.projectState(copyAndAddUserInfo(baseGameState, userID, opponent.Username), username)
 
 		room.AddConnection(opponent.Username, opponent.Connection)
 		room.AddConnection(username, conn)
@@ -114,7 +124,7 @@ func (m *OnlineUsersManager) handlePublicGameRequest(username string, userID str
 			time.Sleep(2 * time.Second)
 
 			// Envoyer le message de début de partie aux deux joueurs
-			if err := opponent.Connection.WriteJSON(WebSocketMessage{
+			if err := opponent.Connection.Send(WebSocketMessage{
 				Type:    PublicGameMatched,
 				Content: string(mustJson(player1GameState)),
 			}); err != nil {
@@ -122,7 +132,7 @@ func (m *OnlineUsersManager) handlePublicGameRequest(username string, userID str
 				return
 			}
 
-			if err := conn.WriteJSON(WebSocketMessage{
+			if err := conn.Send(WebSocketMessage{
 				Type:    PublicGameMatched,
 				Content: string(mustJson(player2GameState)),
 			}); err != nil {
@@ -159,7 +169,7 @@ func (m *OnlineUsersManager) handlePublicQueueLeave(username string) {
 
 	// Notifier le joueur qu'il a quitté la file d'attente
 	if player.Connection != nil {
-		player.Connection.WriteJSON(WebSocketMessage{
+		player.Connection.Send(WebSocketMessage{
 			Type: PublicQueueLeave,
 			Content: string(mustJson(map[string]string{
 				"message": "Vous avez quitté le mode public.",
@@ -185,7 +195,7 @@ func (m *OnlineUsersManager) handlePublicGameTimeout(username string) {
 	m.broadcastOnlineUsers()
 
 	// Notifier le joueur du timeout
-	player.Connection.WriteJSON(WebSocketMessage{
+	player.Connection.Send(WebSocketMessage{
 		Type: PublicGameTimeout,
 		Content: string(mustJson(map[string]string{
 			"message": "Aucun adversaire trouvé. Veuillez réessayer.",
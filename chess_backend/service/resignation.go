@@ -0,0 +1,53 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"fmt"
+)
+
+const (
+	Resign      string = "resign"
+	ResignError string = "resign_error"
+)
+
+// Abandon volontaire : l'adversaire est déclaré vainqueur
+func (room *ChessGameRoom) Resign(username string) error {
+	room.mutex.Lock()
+	if room.IsGameOver {
+		room.mutex.Unlock()
+		return fmt.Errorf("game is over")
+	}
+	color, found := room.playerColor(username)
+	if !found {
+		room.mutex.Unlock()
+		return fmt.Errorf("user %s not in room %s", username, room.RoomID)
+	}
+	room.IsGameOver = true
+	room.mutex.Unlock()
+
+	room.finishGame(chess.Outcome{Termination: chess.Resignation, Winner: color.Other()})
+	return nil
+}
+
+// Départ ou déconnexion en cours de partie : compté comme une défaite.
+// Retourne false si la partie était déjà terminée.
+func (room *ChessGameRoom) forfeit(username string) (chess.Outcome, bool) {
+	room.mutex.Lock()
+	defer room.mutex.Unlock()
+
+	color, found := room.playerColor(username)
+	if !found || room.IsGameOver {
+		return chess.Outcome{}, false
+	}
+
+	outcome := chess.Outcome{Termination: chess.Abandoned, Winner: color.Other()}
+	room.recordOutcome(outcome)
+	return outcome, true
+}
+
+// Traiter le message resign
+func (m *OnlineUsersManager) handleResign(username string, msg WebSocketMessage) {
+	m.handleRoomAction(username, msg, ResignError, func(room *ChessGameRoom) error {
+		return room.Resign(username)
+	})
+}
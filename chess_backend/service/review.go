@@ -0,0 +1,535 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Messages WebSocket de l'analyse d'après-partie
+const (
+	ReviewSubscribe = "review_subscribe"
+	ReviewProgress  = "review_progress"
+	ReviewReady     = "review_ready"
+	ReviewError     = "review_error"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewPending ReviewStatus = "pending"
+	ReviewRunning ReviewStatus = "running"
+	ReviewDone    ReviewStatus = "done"
+	ReviewFailed  ReviewStatus = "failed"
+)
+
+type MoveClassification string
+
+const (
+	MoveBest       MoveClassification = "best"
+	MoveGood       MoveClassification = "good"
+	MoveInaccuracy MoveClassification = "inaccuracy"
+	MoveMistake    MoveClassification = "mistake"
+	MoveBlunder    MoveClassification = "blunder"
+)
+
+// Pertes en centipions à partir desquelles un coup est classé
+const (
+	inaccuracyLoss = 50
+	mistakeLoss    = 100
+	blunderLoss    = 300
+)
+
+// Évaluation maximale retenue : au-delà, la partie est jouée d'avance
+const reviewEvalCap = 1000
+
+// Coup analysé ; les évaluations sont en centipions du point de vue des blancs
+type ReviewedMove struct {
+	Ply            int                `json:"ply"`
+	Color          string             `json:"color"`
+	SAN            string             `json:"san"`
+	UCI            string             `json:"uci"`
+	EvalBefore     int                `json:"evalBefore"`
+	EvalAfter      int                `json:"evalAfter"`
+	MateAfter      int                `json:"mateAfter,omitempty"` // mat annoncé après le coup, positif si les blancs matent
+	BestMove       string             `json:"bestMove,omitempty"`
+	BestSAN        string             `json:"bestSan,omitempty"`
+	CPLoss         int                `json:"cpLoss"`
+	Accuracy       float64            `json:"accuracy"`
+	Classification MoveClassification `json:"classification"`
+}
+
+// Bilan d'un joueur
+type PlayerReview struct {
+	Accuracy     float64 `json:"accuracy"` // pourcentage moyen de précision des coups
+	ACPL         float64 `json:"acpl"`     // perte moyenne en centipions
+	Inaccuracies int     `json:"inaccuracies"`
+	Mistakes     int     `json:"mistakes"`
+	Blunders     int     `json:"blunders"`
+}
+
+// Analyse complète d'une partie, rattachée à la partie archivée
+type GameReview struct {
+	GameID      string         `json:"gameId"`
+	Status      ReviewStatus   `json:"status"`
+	Progress    int            `json:"progress"` // positions analysées
+	Total       int            `json:"total"`
+	Engine      string         `json:"engine,omitempty"`
+	Depth       int            `json:"depth"`
+	Moves       []ReviewedMove `json:"moves,omitempty"`
+	White       PlayerReview   `json:"white"`
+	Black       PlayerReview   `json:"black"`
+	Error       string         `json:"error,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	CompletedAt time.Time      `json:"completedAt,omitempty"`
+}
+
+// File des analyses d'après-partie : un seul travail à la fois, les abonnés
+// reçoivent la progression puis le rapport
+type Reviewer struct {
+	manager     *OnlineUsersManager
+	queue       chan string
+	reviews     map[string]*GameReview // analyses en attente ou en cours
+	subscribers map[string]map[string]bool
+	depth       int
+	moveTime    int // millisecondes par position
+	mutex       sync.Mutex
+}
+
+func NewReviewer(manager *OnlineUsersManager) *Reviewer {
+	depth, err := strconv.Atoi(Getenv("REVIEW_DEPTH", "10"))
+	if err != nil || depth < 1 {
+		depth = 10
+	}
+	moveTime, err := strconv.Atoi(Getenv("REVIEW_MOVE_TIME", "500"))
+	if err != nil || moveTime < 1 {
+		moveTime = 500
+	}
+
+	r := &Reviewer{
+		manager:     manager,
+		queue:       make(chan string, 64),
+		reviews:     make(map[string]*GameReview),
+		subscribers: make(map[string]map[string]bool),
+		depth:       depth,
+		moveTime:    moveTime,
+	}
+	go r.run()
+	return r
+}
+
+// Programmer l'analyse d'une partie archivée ; les abonnés sont prévenus de sa progression.
+// Retourne l'analyse existante si elle est déjà faite ou en cours.
+func (r *Reviewer) Enqueue(gameID string, subscribers ...string) GameReview {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, username := range subscribers {
+		r.subscribeLocked(gameID, username)
+	}
+	if review, exists := r.reviews[gameID]; exists {
+		return *review
+	}
+
+	review := &GameReview{GameID: gameID, Status: ReviewPending, CreatedAt: time.Now()}
+	r.reviews[gameID] = review
+	// Ne pas bloquer l'appelant (qui peut tenir le verrou du RoomManager) si la file est pleine
+	go func() { r.queue <- gameID }()
+	return *review
+}
+
+func (r *Reviewer) Subscribe(gameID, username string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.subscribeLocked(gameID, username)
+}
+
+func (r *Reviewer) subscribeLocked(gameID, username string) {
+	if r.subscribers[gameID] == nil {
+		r.subscribers[gameID] = make(map[string]bool)
+	}
+	r.subscribers[gameID][username] = true
+}
+
+// Analyse en cours, ou rapport rattaché à la partie archivée
+func (r *Reviewer) Get(gameID string) (GameReview, bool) {
+	r.mutex.Lock()
+	review, exists := r.reviews[gameID]
+	if exists {
+		copied := *review
+		r.mutex.Unlock()
+		return copied, true
+	}
+	r.mutex.Unlock()
+
+	game, err := r.manager.gameArchive.GetGame(gameID)
+	if err != nil || game.Review == nil {
+		return GameReview{}, false
+	}
+	return *game.Review, true
+}
+
+func (r *Reviewer) run() {
+	for gameID := range r.queue {
+		review := r.review(gameID)
+
+		if review.Status == ReviewDone {
+			if err := r.manager.gameArchive.SetReview(gameID, review); err != nil {
+				log.Printf("Error saving review of game %s: %v", gameID, err)
+			}
+		} else {
+			log.Printf("Review of game %s failed: %s", gameID, review.Error)
+		}
+
+		r.mutex.Lock()
+		delete(r.reviews, gameID)
+		subscribers := r.subscribers[gameID]
+		delete(r.subscribers, gameID)
+		r.mutex.Unlock()
+
+		messageType := ReviewReady
+		if review.Status == ReviewFailed {
+			messageType = ReviewError
+		}
+		r.notify(setKeys(subscribers), WebSocketMessage{
+			Type:    messageType,
+			Content: string(mustJson(review)),
+		})
+	}
+}
+
+// Évaluer chaque position de la partie puis noter chaque coup
+func (r *Reviewer) review(gameID string) *GameReview {
+	r.mutex.Lock()
+	review := r.reviews[gameID]
+	r.mutex.Unlock()
+
+	fail := func(err error) *GameReview {
+		r.mutex.Lock()
+		review.Status = ReviewFailed
+		review.Error = err.Error()
+		copied := *review
+		r.mutex.Unlock()
+		return &copied
+	}
+
+	game, err := r.manager.gameArchive.GetGame(gameID)
+	if err != nil {
+		return fail(err)
+	}
+
+	r.mutex.Lock()
+	review.Status = ReviewRunning
+	review.Total = len(game.Moves) + 1
+	review.Depth = r.depth
+	r.mutex.Unlock()
+
+	// Une évaluation par position, de la position de départ à la position finale
+	evals := make([]AnalysisLine, len(game.Moves)+1)
+	best := make([]string, len(game.Moves)+1)
+	for ply := range evals {
+		position, err := archivedPosition(game, ply)
+		if err != nil {
+			return fail(err)
+		}
+		line, bestMove, engineName, err := r.evaluate(position, game.Variant)
+		if err != nil {
+			return fail(fmt.Errorf("ply %d: %v", ply, err))
+		}
+		evals[ply], best[ply] = line, bestMove
+
+		r.mutex.Lock()
+		review.Progress = ply + 1
+		if engineName != "" {
+			review.Engine = engineName
+		}
+		progress := map[string]interface{}{
+			"gameId":   gameID,
+			"progress": review.Progress,
+			"total":    review.Total,
+		}
+		subscribers := r.subscribersOf(gameID)
+		r.mutex.Unlock()
+
+		r.notify(subscribers, WebSocketMessage{
+			Type:    ReviewProgress,
+			Content: string(mustJson(progress)),
+		})
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	review.Moves = reviewMoves(game, evals, best)
+	review.White = playerReview(review.Moves, chess.White.String())
+	review.Black = playerReview(review.Moves, chess.Black.String())
+	review.Status = ReviewDone
+	review.CompletedAt = time.Now()
+	copied := *review
+	return &copied
+}
+
+// Évaluation d'une position : résultat de la partie si elle est terminée, sinon analyse du moteur
+func (r *Reviewer) evaluate(position *chess.Position, variant string) (AnalysisLine, string, string, error) {
+	if outcome, over := position.Outcome(); over {
+		line := AnalysisLine{}
+		if !outcome.Draw {
+			line.Score = reviewEvalCap
+			if outcome.Winner == chess.Black {
+				line.Score = -reviewEvalCap
+			}
+		}
+		return line, "", "", nil
+	}
+
+	result, err := r.manager.analyzer.Analyse(context.Background(), position, variant, AnalysisRequest{
+		Depth:    r.depth,
+		MoveTime: r.moveTime,
+	}, nil)
+	if err != nil {
+		return AnalysisLine{}, "", "", err
+	}
+	if len(result.Lines) == 0 {
+		return AnalysisLine{}, "", "", fmt.Errorf("engine returned no line")
+	}
+	return result.Lines[0], result.BestMove, result.Engine, nil
+}
+
+// Noter chaque coup à partir des évaluations avant et après
+func reviewMoves(game *ArchivedGame, evals []AnalysisLine, best []string) []ReviewedMove {
+	moves := make([]ReviewedMove, 0, len(game.Moves))
+	for i, move := range game.Moves {
+		before, after := reviewEval(evals[i]), reviewEval(evals[i+1])
+		color := chess.White
+		if position, err := archivedPosition(game, i); err == nil {
+			color = position.Turn()
+		}
+
+		// Perte du point de vue du joueur qui a joué
+		loss := before - after
+		if color == chess.Black {
+			loss = after - before
+		}
+		if loss < 0 {
+			loss = 0
+		}
+
+		reviewed := ReviewedMove{
+			Ply:        move.Ply,
+			Color:      color.String(),
+			SAN:        move.SAN,
+			UCI:        move.UCI,
+			EvalBefore: before,
+			EvalAfter:  after,
+			MateAfter:  evals[i+1].Mate,
+			BestMove:   best[i],
+			CPLoss:     loss,
+			Accuracy:   moveAccuracy(before, after, color),
+		}
+		if best[i] != "" {
+			if position, err := archivedPosition(game, i); err == nil {
+				if bestMove, err := position.ParseUCI(best[i]); err == nil {
+					reviewed.BestSAN = position.SAN(bestMove)
+				}
+			}
+		}
+		reviewed.Classification = classifyMove(reviewed)
+		moves = append(moves, reviewed)
+	}
+	return moves
+}
+
+// Évaluation bornée : un mat compte comme l'évaluation maximale
+func reviewEval(line AnalysisLine) int {
+	switch {
+	case line.Mate > 0:
+		return reviewEvalCap
+	case line.Mate < 0:
+		return -reviewEvalCap
+	case line.Score > reviewEvalCap:
+		return reviewEvalCap
+	case line.Score < -reviewEvalCap:
+		return -reviewEvalCap
+	}
+	return line.Score
+}
+
+func classifyMove(move ReviewedMove) MoveClassification {
+	switch {
+	case move.BestMove != "" && move.UCI == move.BestMove:
+		return MoveBest
+	case move.CPLoss >= blunderLoss:
+		return MoveBlunder
+	case move.CPLoss >= mistakeLoss:
+		return MoveMistake
+	case move.CPLoss >= inaccuracyLoss:
+		return MoveInaccuracy
+	}
+	return MoveGood
+}
+
+// Chances de gain (0 à 100) du camp c selon l'évaluation des blancs
+func winPercent(eval int, c chess.Color) float64 {
+	if c == chess.Black {
+		eval = -eval
+	}
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*float64(eval)))-1)
+}
+
+// Précision d'un coup d'après la baisse des chances de gain qu'il provoque
+func moveAccuracy(before, after int, c chess.Color) float64 {
+	drop := winPercent(before, c) - winPercent(after, c)
+	if drop < 0 {
+		drop = 0
+	}
+	accuracy := 103.1668*math.Exp(-0.04354*drop) - 3.1669
+	return math.Round(math.Max(0, math.Min(100, accuracy))*10) / 10
+}
+
+func playerReview(moves []ReviewedMove, color string) PlayerReview {
+	var review PlayerReview
+	var accuracy, loss float64
+	count := 0
+	for _, move := range moves {
+		if move.Color != color {
+			continue
+		}
+		count++
+		accuracy += move.Accuracy
+		loss += float64(move.CPLoss)
+		switch move.Classification {
+		case MoveInaccuracy:
+			review.Inaccuracies++
+		case MoveMistake:
+			review.Mistakes++
+		case MoveBlunder:
+			review.Blunders++
+		}
+	}
+	if count > 0 {
+		review.Accuracy = math.Round(accuracy/float64(count)*10) / 10
+		review.ACPL = math.Round(loss/float64(count)*10) / 10
+	}
+	return review
+}
+
+// Doit être appelée avec r.mutex verrouillé
+func (r *Reviewer) subscribersOf(gameID string) []string {
+	var usernames []string
+	for username := range r.subscribers[gameID] {
+		usernames = append(usernames, username)
+	}
+	return usernames
+}
+
+func (r *Reviewer) notify(usernames []string, message WebSocketMessage) {
+	for _, username := range usernames {
+		r.manager.sendToUser(username, message)
+	}
+}
+
+func setKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Programmer l'analyse d'une partie archivée si elle n'est pas déjà faite
+func (r *Reviewer) request(gameID string, subscribers ...string) (GameReview, error) {
+	if review, exists := r.Get(gameID); exists && review.Status != ReviewFailed {
+		if review.Status != ReviewDone {
+			for _, username := range subscribers {
+				r.Subscribe(gameID, username)
+			}
+		}
+		return review, nil
+	}
+
+	game, err := r.manager.gameArchive.GetGame(gameID)
+	if err != nil {
+		return GameReview{}, err
+	}
+	if len(game.Moves) == 0 {
+		return GameReview{}, fmt.Errorf("game has no moves")
+	}
+	return r.Enqueue(gameID, subscribers...), nil
+}
+
+// Message review_subscribe {"gameId": ...} : rapport immédiat s'il est prêt,
+// sinon abonnement à la progression de l'analyse
+func (m *OnlineUsersManager) handleReviewSubscribe(username string, msg WebSocketMessage) {
+	var req struct {
+		GameID string `json:"gameId"`
+	}
+	if err := json.Unmarshal([]byte(msg.Content), &req); err != nil {
+		log.Printf("Error parsing review subscription: %v", err)
+		return
+	}
+
+	review, err := m.reviewer.request(req.GameID, username)
+	if err != nil {
+		m.sendToUser(username, WebSocketMessage{
+			Type: ReviewError,
+			Content: string(mustJson(map[string]string{
+				"gameId": req.GameID,
+				"error":  err.Error(),
+			})),
+		})
+		return
+	}
+
+	messageType := ReviewProgress
+	if review.Status == ReviewDone {
+		messageType = ReviewReady
+	}
+	m.sendToUser(username, WebSocketMessage{
+		Type:    messageType,
+		Content: string(mustJson(review)),
+	})
+}
+
+// GET /games/{id}/review : rapport d'analyse, ou 202 avec l'avancement tant qu'il n'est pas prêt
+func GameReviewHandler(onlineUsersManager *OnlineUsersManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID := mux.Vars(r)["id"]
+
+		review, exists := onlineUsersManager.reviewer.Get(gameID)
+		if !exists {
+			http.Error(w, "review not found", http.StatusNotFound)
+			return
+		}
+		writeReview(w, review)
+	}
+}
+
+// POST /games/{id}/review : lancer l'analyse d'une partie archivée (parties importées, échec précédent)
+func GameReviewRequestHandler(onlineUsersManager *OnlineUsersManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		gameID := mux.Vars(r)["id"]
+
+		review, err := onlineUsersManager.reviewer.request(gameID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeReview(w, review)
+	}
+}
+
+func writeReview(w http.ResponseWriter, review GameReview) {
+	w.Header().Set("Content-Type", "application/json")
+	if review.Status != ReviewDone {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(review)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"fmt"
+	"time"
+)
+
+const (
+	TakebackRequest  string = "takeback_request"
+	TakebackAccept   string = "takeback_accept"
+	TakebackDecline  string = "takeback_decline"
+	TakebackDeclined string = "takeback_declined"
+	TakebackExpired  string = "takeback_expired"
+	TakebackError    string = "takeback_error"
+	GameSync         string = "game_sync"
+)
+
+// Demander à l'adversaire d'annuler son dernier coup (interdit en partie classée)
+func (room *ChessGameRoom) RequestTakeback(username string) error {
+	room.mutex.Lock()
+	if room.IsGameOver {
+		room.mutex.Unlock()
+		return fmt.Errorf("game is over")
+	}
+	if room.Rated {
+		room.mutex.Unlock()
+		return fmt.Errorf("takebacks are disabled in rated games")
+	}
+	if room.partner != nil {
+		room.mutex.Unlock()
+		return fmt.Errorf("takebacks are disabled in bughouse games")
+	}
+	color, found := room.playerColor(username)
+	if !found {
+		room.mutex.Unlock()
+		return fmt.Errorf("user %s not in room %s", username, room.RoomID)
+	}
+	if room.takebackRequestBy != "" {
+		room.mutex.Unlock()
+		return fmt.Errorf("a takeback request is already pending")
+	}
+	if room.takebackPlies(color) > len(room.game.Moves()) {
+		room.mutex.Unlock()
+		return fmt.Errorf("no move to take back")
+	}
+	room.takebackRequestBy = username
+	opponent, _ := room.GetOtherPlayer(username)
+	room.mutex.Unlock()
+
+	room.sendToPlayer(opponent, WebSocketMessage{
+		Type: TakebackRequest,
+		Content: string(mustJson(map[string]string{
+			"gameId":       room.RoomID,
+			"fromUsername": username,
+		})),
+	})
+	return nil
+}
+
+// Accepter la demande : annuler les demi-coups et resynchroniser les deux joueurs
+func (room *ChessGameRoom) AcceptTakeback(username string) error {
+	room.mutex.Lock()
+	requestedBy := room.takebackRequestBy
+	if room.IsGameOver {
+		room.mutex.Unlock()
+		return fmt.Errorf("game is over")
+	}
+	if _, found := room.playerColor(username); !found {
+		room.mutex.Unlock()
+		return fmt.Errorf("user %s not in room %s", username, room.RoomID)
+	}
+	if requestedBy == "" || requestedBy == username {
+		room.mutex.Unlock()
+		return fmt.Errorf("no takeback request to accept")
+	}
+	room.takebackRequestBy = ""
+	// Une proposition de nulle portait sur la position annulée
+	room.drawOfferBy = ""
+
+	color, _ := room.playerColor(requestedBy)
+	undone := 0
+	for plies := room.takebackPlies(color); plies > 0; plies-- {
+		if !room.game.Undo() {
+			break
+		}
+		undone++
+		if len(room.Moves) > 0 {
+			room.Moves = room.Moves[:len(room.Moves)-1]
+		}
+	}
+	room.PositionFEN = room.game.Position().FEN()
+	room.IsWhitesTurn = room.game.Position().Turn() == chess.White
+	room.Pockets = pocketsOf(room.game.Position())
+	if room.Timer != nil {
+		room.Timer.Resync(room.game.Position().Turn(), time.Now(), undone)
+	}
+	room.mutex.Unlock()
+
+	if room.Timer != nil {
+		room.Timer.broadcastTimeUpdate()
+	}
+	room.BroadcastProjected(room.syncMessageFor)
+	return nil
+}
+
+// Refuser la demande de l'adversaire
+func (room *ChessGameRoom) DeclineTakeback(username string) error {
+	room.mutex.Lock()
+	if _, found := room.playerColor(username); !found {
+		room.mutex.Unlock()
+		return fmt.Errorf("user %s not in room %s", username, room.RoomID)
+	}
+	requestedBy := room.takebackRequestBy
+	if requestedBy == "" || requestedBy == username {
+		room.mutex.Unlock()
+		return fmt.Errorf("no takeback request to decline")
+	}
+	room.takebackRequestBy = ""
+	room.mutex.Unlock()
+
+	room.sendToPlayer(requestedBy, WebSocketMessage{
+		Type: TakebackDeclined,
+		Content: string(mustJson(map[string]string{
+			"gameId":       room.RoomID,
+			"fromUsername": username,
+		})),
+	})
+	return nil
+}
+
+// Nombre de demi-coups à annuler pour rendre la main au demandeur :
+// un seul s'il vient de jouer, deux si l'adversaire a déjà répondu.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) takebackPlies(requester chess.Color) int {
+	if room.game.Position().Turn() == requester {
+		return 2
+	}
+	return 1
+}
+
+// Toute demande en attente expire dès qu'un coup est joué.
+// Doit être appelée avec room.mutex verrouillé.
+func (room *ChessGameRoom) expireTakebackRequest() {
+	if room.takebackRequestBy == "" {
+		return
+	}
+	room.takebackRequestBy = ""
+
+	message := WebSocketMessage{
+		Type: TakebackExpired,
+		Content: string(mustJson(map[string]string{
+			"gameId": room.RoomID,
+		})),
+	}
+	for _, conn := range room.Connections {
+		conn.Send(message)
+	}
+}
+
+// Message de resynchronisation complet de l'état de la partie, tel que username le voit
+func (room *ChessGameRoom) syncMessageFor(username string) WebSocketMessage {
+	room.mutex.RLock()
+	state := map[string]interface{}{
+		"gameId":       room.RoomID,
+		"fen":          room.fenFor(username),
+		"isWhitesTurn": room.IsWhitesTurn,
+		"isGameOver":   room.IsGameOver,
+		"moves":        room.movesFor(username),
+		"timeControl":  room.TimeControl,
+	}
+	if room.Pockets != nil {
+		state["pockets"] = room.Pockets
+	}
+	room.mutex.RUnlock()
+
+	if room.Timer != nil {
+		whiteTime, blackTime := room.Timer.Remaining()
+		state["whiteTime"] = int(whiteTime / time.Second)
+		state["blackTime"] = int(blackTime / time.Second)
+		state["whiteMs"] = whiteTime.Milliseconds()
+		state["blackMs"] = blackTime.Milliseconds()
+		state["clock"] = room.clockState()
+	}
+
+	return WebSocketMessage{
+		Type:    GameSync,
+		Content: string(mustJson(state)),
+	}
+}
+
+// Traiter les messages takeback_request, takeback_accept et takeback_decline
+func (m *OnlineUsersManager) handleTakebackMessage(username string, msg WebSocketMessage) {
+	m.handleRoomAction(username, msg, TakebackError, func(room *ChessGameRoom) error {
+		switch msg.Type {
+		case TakebackRequest:
+			return room.RequestTakeback(username)
+		case TakebackAccept:
+			return room.AcceptTakeback(username)
+		case TakebackDecline:
+			return room.DeclineTakeback(username)
+		}
+		return nil
+	})
+}
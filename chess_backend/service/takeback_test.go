@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakebackRefusedToNonPlayer(t *testing.T) {
+	room := newTestRoom(t)
+	room.mutex.Lock()
+	_, err := room.applyMove("alice", "e2e4", time.Now())
+	room.mutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := room.RequestTakeback("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := room.AcceptTakeback("mallory"); err == nil {
+		t.Error("a user outside the room accepted the takeback")
+	}
+	if err := room.DeclineTakeback("mallory"); err == nil {
+		t.Error("a user outside the room declined the takeback")
+	}
+	if len(room.Moves) != 1 || room.takebackRequestBy != "alice" {
+		t.Errorf("takeback changed by a non-player: %d moves, requested by %q", len(room.Moves), room.takebackRequestBy)
+	}
+
+	if err := room.AcceptTakeback("bob"); err != nil {
+		t.Fatalf("opponent could not accept the takeback: %v", err)
+	}
+	if len(room.Moves) != 0 || !room.IsWhitesTurn {
+		t.Errorf("after takeback: %d moves, white to move %v", len(room.Moves), room.IsWhitesTurn)
+	}
+}
+
+func TestRoomActionRefusedToNonPlayer(t *testing.T) {
+	room := newTestRoom(t)
+	m := &OnlineUsersManager{
+		connections: make(map[string]*SafeConn),
+		roomManager: &RoomManager{rooms: map[string]*ChessGameRoom{room.RoomID: room}},
+	}
+	msg := WebSocketMessage{Type: TakebackAccept, Content: string(mustJson(map[string]string{"gameId": room.RoomID}))}
+
+	called := ""
+	action := func(username string) func(*ChessGameRoom) error {
+		return func(*ChessGameRoom) error {
+			called = username
+			return nil
+		}
+	}
+	m.handleRoomAction("mallory", msg, TakebackError, action("mallory"))
+	if called != "" {
+		t.Errorf("room action run for a user outside the room")
+	}
+	m.handleRoomAction("bob", msg, TakebackError, action("bob"))
+	if called != "bob" {
+		t.Errorf("room action not run for a player")
+	}
+}
@@ -11,6 +11,10 @@ type TempRoom struct {
 	CreatedAt   time.Time
 	WhitePlayer OnlineUser
 	BlackPlayer OnlineUser
+	FEN         string // position de départ proposée dans l'invitation
+	Variant     string
+	TimeControl string
+	Rated       bool // fixé par l'invitation : l'invité ne peut pas le modifier en acceptant
 }
 
 type TemporaryRoomManager struct {
@@ -47,6 +51,10 @@ func (trm *TemporaryRoomManager) CreateTempRoom(invitation InvitationMessage, ti
 			ID:       invitation.ToUserID,
 			Username: invitation.ToUsername,
 		},
+		FEN:       invitation.FEN,
+		Variant:   invitation.Variant,
+		TimeControl: invitation.TimeControl,
+		Rated:     invitation.Rated,
 		CreatedAt: time.Now(),
 	}
 
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Manière dont la pendule traite le temps de chaque coup
+type ClockMode string
+
+const (
+	// Incrément Fischer (mort subite si l'incrément est nul) : ajouté après chaque coup
+	ClockIncrement ClockMode = "increment"
+	// Délai simple (US) : la pendule ne décompte qu'après les N premières secondes du coup
+	ClockSimpleDelay ClockMode = "delay"
+	// Délai Bronstein : jusqu'à N secondes consommées sont rendues après le coup
+	ClockBronstein ClockMode = "bronstein"
+)
+
+// Suffixe de l'incrément indiquant le mode dans la notation d'une cadence ("5+d3", "5+b3")
+var clockModeSuffixes = map[ClockMode]string{
+	ClockIncrement:   "",
+	ClockSimpleDelay: "d",
+	ClockBronstein:   "b",
+}
+
+// Période d'une cadence : Base secondes pour jouer Moves coups (0 = jusqu'à la fin de la partie)
+type TimeStage struct {
+	Moves     int `json:"moves,omitempty"`
+	Base      int `json:"base"`      // secondes ajoutées au début de la période
+	Increment int `json:"increment"` // incrément ou délai par coup, en secondes
+}
+
+// Cadence d'une partie : une ou plusieurs périodes, la dernière couvrant le reste de la partie
+type TimeControl struct {
+	Mode   ClockMode
+	Stages []TimeStage
+}
+
+// Cadence des parties sans cadence demandée
+var DefaultTimeControl = TimeControl{Mode: ClockIncrement, Stages: []TimeStage{{Base: 10 * 60}}}
+
+// Limites acceptées pour les cadences demandées par les clients
+const (
+	maxTimeControlBase      = 3 * 60 * 60
+	maxTimeControlIncrement = 3 * 60
+	maxTimeControlStages    = 3
+)
+
+// Catégories de cadence, d'après la durée estimée d'une partie de 40 coups
+const (
+	CategoryBullet    = "bullet"
+	CategoryBlitz     = "blitz"
+	CategoryRapid     = "rapid"
+	CategoryClassical = "classical"
+)
+
+// Analyser une cadence. Chaque période s'écrit "[coups/]minutes[+incrément]",
+// l'incrément préfixé de d (délai simple) ou b (Bronstein), les périodes séparées par des virgules :
+// "3+2", "10+5", "5+d3", "15+b10", "40/90+30,30+30".
+func ParseTimeControl(s string) (TimeControl, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DefaultTimeControl, nil
+	}
+
+	var tc TimeControl
+	for _, part := range strings.Split(s, ",") {
+		stage, mode, err := parseTimeStage(strings.TrimSpace(part))
+		if err != nil {
+			return TimeControl{}, fmt.Errorf("invalid time control %q: %v", s, err)
+		}
+		// Une période sans incrément s'accorde avec le mode des autres
+		if mode != "" {
+			if tc.Mode != "" && mode != tc.Mode {
+				return TimeControl{}, fmt.Errorf("invalid time control %q: stages use different clock modes", s)
+			}
+			tc.Mode = mode
+		}
+		tc.Stages = append(tc.Stages, stage)
+	}
+	if tc.Mode == "" {
+		tc.Mode = ClockIncrement
+	}
+	if err := tc.Validate(); err != nil {
+		return TimeControl{}, err
+	}
+	return tc, nil
+}
+
+// Période et mode de son incrément ("" si la période n'a pas d'incrément)
+func parseTimeStage(s string) (TimeStage, ClockMode, error) {
+	var stage TimeStage
+	var mode ClockMode
+
+	if movesPart, rest, found := strings.Cut(s, "/"); found {
+		moves, err := strconv.Atoi(strings.TrimSpace(movesPart))
+		if err != nil {
+			return stage, mode, fmt.Errorf("invalid move count %q", movesPart)
+		}
+		stage.Moves, s = moves, rest
+	}
+
+	minutesPart, incrementPart, hasIncrement := strings.Cut(s, "+")
+	minutes, err := strconv.ParseFloat(strings.TrimSpace(minutesPart), 64)
+	if err != nil {
+		return stage, mode, fmt.Errorf("invalid minutes %q", minutesPart)
+	}
+	stage.Base = int(minutes * 60)
+
+	if hasIncrement {
+		mode = ClockIncrement
+		incrementPart = strings.TrimSpace(incrementPart)
+		for candidate, suffix := range clockModeSuffixes {
+			if suffix != "" && strings.HasPrefix(incrementPart, suffix) {
+				mode, incrementPart = candidate, incrementPart[len(suffix):]
+				break
+			}
+		}
+		if stage.Increment, err = strconv.Atoi(incrementPart); err != nil {
+			return stage, mode, fmt.Errorf("invalid increment %q", incrementPart)
+		}
+	}
+	return stage, mode, nil
+}
+
+func (tc TimeControl) Validate() error {
+	if _, known := clockModeSuffixes[tc.Mode]; !known {
+		return fmt.Errorf("unknown clock mode %q", tc.Mode)
+	}
+	if len(tc.Stages) == 0 || len(tc.Stages) > maxTimeControlStages {
+		return fmt.Errorf("a time control has between 1 and %d stages", maxTimeControlStages)
+	}
+	for i, stage := range tc.Stages {
+		if stage.Base <= 0 || stage.Base > maxTimeControlBase {
+			return fmt.Errorf("base time must be between 1 second and %d minutes", maxTimeControlBase/60)
+		}
+		if stage.Increment < 0 || stage.Increment > maxTimeControlIncrement {
+			return fmt.Errorf("increment must be between 0 and %d seconds", maxTimeControlIncrement)
+		}
+		last := i == len(tc.Stages)-1
+		if last && stage.Moves != 0 {
+			return fmt.Errorf("the last stage must cover the rest of the game")
+		}
+		if !last && stage.Moves <= 0 {
+			return fmt.Errorf("every stage but the last needs a move count")
+		}
+	}
+	return nil
+}
+
+// Temps de départ de chaque joueur, en secondes
+func (tc TimeControl) Base() int {
+	return tc.Stages[0].Base
+}
+
+// Période en cours pour un joueur ayant joué moves coups
+func (tc TimeControl) StageAt(moves int) int {
+	stage := 0
+	for stage < len(tc.Stages)-1 && moves >= tc.Stages[stage].Moves {
+		moves -= tc.Stages[stage].Moves
+		stage++
+	}
+	return stage
+}
+
+// Notation "[coups/]minutes+incrément" utilisée par les clients
+func (tc TimeControl) String() string {
+	suffix := clockModeSuffixes[tc.Mode]
+	parts := make([]string, len(tc.Stages))
+	for i, stage := range tc.Stages {
+		minutes := strconv.FormatFloat(float64(stage.Base)/60, 'f', -1, 64)
+		parts[i] = fmt.Sprintf("%s+%s%d", minutes, suffix, stage.Increment)
+		if stage.Moves > 0 {
+			parts[i] = fmt.Sprintf("%d/%s", stage.Moves, parts[i])
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Valeur de la balise PGN TimeControl (en secondes, périodes séparées par ":") :
+// "600", "180+2", "40/5400+30:1800+30" ; les délais s'écrivent "300+d3" ou "300+b3"
+func (tc TimeControl) PGN() string {
+	suffix := clockModeSuffixes[tc.Mode]
+	parts := make([]string, len(tc.Stages))
+	for i, stage := range tc.Stages {
+		parts[i] = strconv.Itoa(stage.Base)
+		if stage.Increment != 0 {
+			parts[i] += fmt.Sprintf("+%s%d", suffix, stage.Increment)
+		}
+		if stage.Moves > 0 {
+			parts[i] = fmt.Sprintf("%d/%s", stage.Moves, parts[i])
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// Catégorie de la cadence pour l'affichage (bullet, blitz, rapide, classique)
+func (tc TimeControl) Category() string {
+	first := tc.Stages[0]
+	estimated := first.Base + 40*first.Increment
+	switch {
+	case estimated < 3*60:
+		return CategoryBullet
+	case estimated < 8*60:
+		return CategoryBlitz
+	case estimated < 25*60:
+		return CategoryRapid
+	}
+	return CategoryClassical
+}
+
+// Forme envoyée aux clients dans game_start et time_update
+func (tc TimeControl) MarshalJSON() ([]byte, error) {
+	if len(tc.Stages) == 0 {
+		return []byte("null"), nil
+	}
+	return json.Marshal(map[string]interface{}{
+		"name":      tc.String(),
+		"mode":      tc.Mode,
+		"base":      tc.Base(),
+		"increment": tc.Stages[0].Increment,
+		"stages":    tc.Stages,
+		"category":  tc.Category(),
+		"pgn":       tc.PGN(),
+	})
+}
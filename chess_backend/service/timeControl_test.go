@@ -0,0 +1,190 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTimeControl(t *testing.T) {
+	cases := []struct {
+		input  string
+		want   TimeControl
+		name   string
+		pgn    string
+		failed bool
+	}{
+		{input: "", want: DefaultTimeControl, name: "10+0", pgn: "600"},
+		{input: "3+2", want: TimeControl{Mode: ClockIncrement, Stages: []TimeStage{{Base: 180, Increment: 2}}},
+			name: "3+2", pgn: "180+2"},
+		{input: "0.5+0", want: TimeControl{Mode: ClockIncrement, Stages: []TimeStage{{Base: 30}}},
+			name: "0.5+0", pgn: "30"},
+		{input: "5+d3", want: TimeControl{Mode: ClockSimpleDelay, Stages: []TimeStage{{Base: 300, Increment: 3}}},
+			name: "5+d3", pgn: "300+d3"},
+		{input: "15+b10", want: TimeControl{Mode: ClockBronstein, Stages: []TimeStage{{Base: 900, Increment: 10}}},
+			name: "15+b10", pgn: "900+b10"},
+		{input: "40/90+30,30+30", want: TimeControl{Mode: ClockIncrement, Stages: []TimeStage{
+			{Moves: 40, Base: 5400, Increment: 30}, {Base: 1800, Increment: 30}}},
+			name: "40/90+30,30+30", pgn: "40/5400+30:1800+30"},
+		// Une période sans incrément prend le mode des autres
+		{input: "40/90,30+d5", want: TimeControl{Mode: ClockSimpleDelay, Stages: []TimeStage{
+			{Moves: 40, Base: 5400}, {Base: 1800, Increment: 5}}},
+			name: "40/90+d0,30+d5", pgn: "40/5400:1800+d5"},
+
+		{input: "abc", failed: true},
+		{input: "3+", failed: true},
+		{input: "3+x2", failed: true},
+		{input: "0+2", failed: true},
+		{input: "3+-1", failed: true},
+		{input: "181+0", failed: true},
+		{input: "3+181", failed: true},
+		{input: "40/90+30", failed: true},
+		{input: "90+30,30+30", failed: true},
+		{input: "x/90+30,30+30", failed: true},
+		{input: "5+d3,3+b2", failed: true},
+		{input: "1/1+1,1/1+1,1/1+1,1+1", failed: true},
+	}
+
+	for _, c := range cases {
+		tc, err := ParseTimeControl(c.input)
+		if c.failed {
+			if err == nil {
+				t.Errorf("ParseTimeControl(%q) = %v, want error", c.input, tc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTimeControl(%q): %v", c.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(tc, c.want) {
+			t.Errorf("ParseTimeControl(%q) = %+v, want %+v", c.input, tc, c.want)
+		}
+		if got := tc.String(); got != c.name {
+			t.Errorf("ParseTimeControl(%q).String() = %q, want %q", c.input, got, c.name)
+		}
+		if got := tc.PGN(); got != c.pgn {
+			t.Errorf("ParseTimeControl(%q).PGN() = %q, want %q", c.input, got, c.pgn)
+		}
+	}
+}
+
+func TestStageAt(t *testing.T) {
+	tc, err := ParseTimeControl("40/90+30,20/60+30,30+30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for moves, want := range map[int]int{0: 0, 39: 0, 40: 1, 59: 1, 60: 2, 200: 2} {
+		if got := tc.StageAt(moves); got != want {
+			t.Errorf("StageAt(%d) = %d, want %d", moves, got, want)
+		}
+	}
+}
+
+// Pendule en marche depuis start, sans deadline ni room active
+func newTestTimer(t *testing.T, timeControl string, start time.Time) *ChessTimer {
+	t.Helper()
+	tc, err := ParseTimeControl(timeControl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := NewChessTimer(&ChessGameRoom{RoomID: "test", IsWhitesTurn: true}, tc)
+	ct.isRunning = true
+	ct.turnStart = start
+	t.Cleanup(func() {
+		ct.mutex.Lock()
+		ct.isRunning = false
+		if ct.deadline != nil {
+			ct.deadline.Stop()
+		}
+		ct.mutex.Unlock()
+	})
+	return ct
+}
+
+func TestSwitchTurn(t *testing.T) {
+	cases := []struct {
+		timeControl string
+		thinking    []time.Duration // durée de chaque demi-coup, blancs d'abord
+		want        [2]time.Duration
+	}{
+		// Fischer : l'incrément s'ajoute après chaque coup
+		{"3+2", []time.Duration{5 * time.Second, 10 * time.Second}, [2]time.Duration{177 * time.Second, 172 * time.Second}},
+		// Délai simple : les 3 premières secondes ne sont pas décomptées
+		{"5+d3", []time.Duration{2 * time.Second, 5 * time.Second}, [2]time.Duration{300 * time.Second, 298 * time.Second}},
+		// Bronstein : jusqu'à 10 secondes consommées sont rendues
+		{"15+b10", []time.Duration{4 * time.Second, 12 * time.Second}, [2]time.Duration{900 * time.Second, 898 * time.Second}},
+		// Mort subite
+		{"1+0", []time.Duration{1500 * time.Millisecond}, [2]time.Duration{58500 * time.Millisecond, 60 * time.Second}},
+	}
+
+	for _, c := range cases {
+		start := time.Now()
+		ct := newTestTimer(t, c.timeControl, start)
+		at := start
+		for _, thinking := range c.thinking {
+			at = at.Add(thinking)
+			ct.SwitchTurn(at, 0)
+		}
+		if ct.remaining != c.want {
+			t.Errorf("%s: remaining = %v, want %v", c.timeControl, ct.remaining, c.want)
+		}
+	}
+}
+
+func TestSwitchTurnNextStage(t *testing.T) {
+	start := time.Now()
+	ct := newTestTimer(t, "40/90+30,30+30", start)
+
+	// 40 coups des blancs en une seconde chacun, réponses immédiates des noirs
+	at := start
+	for move := 1; move <= 40; move++ {
+		at = at.Add(time.Second)
+		ct.SwitchTurn(at, 0)
+		ct.SwitchTurn(at, 0)
+		if move == 39 && ct.remaining[chess.White] != (5400+39*29)*time.Second {
+			t.Fatalf("after 39 moves: white has %v", ct.remaining[chess.White])
+		}
+	}
+	if want := (5400 + 40*29 + 1800) * time.Second; ct.remaining[chess.White] != want {
+		t.Errorf("after 40 moves: white has %v, want %v", ct.remaining[chess.White], want)
+	}
+	if ct.movesPlayed[chess.White] != 40 || ct.timeControl.StageAt(ct.movesPlayed[chess.White]) != 1 {
+		t.Errorf("white played %d moves, want 40 in the second stage", ct.movesPlayed[chess.White])
+	}
+
+	// Reprise des deux derniers demi-coups : retour dans la première période, sans les incréments
+	ct.Resync(chess.White, at, 2)
+	if want := (5400 + 39*29 - 1) * time.Second; ct.remaining[chess.White] != want {
+		t.Errorf("after takeback: white has %v, want %v", ct.remaining[chess.White], want)
+	}
+	if want := (5400 + 39*30) * time.Second; ct.remaining[chess.Black] != want {
+		t.Errorf("after takeback: black has %v, want %v", ct.remaining[chess.Black], want)
+	}
+	if ct.movesPlayed != [2]int{39, 39} {
+		t.Errorf("after takeback: moves played = %v, want [39 39]", ct.movesPlayed)
+	}
+}
+
+func TestHasFlagged(t *testing.T) {
+	start := time.Now()
+	ct := newTestTimer(t, "1+0", start)
+
+	if ct.HasFlagged(chess.White, start.Add(59*time.Second), 0) {
+		t.Error("white flagged with one second left")
+	}
+	if !ct.HasFlagged(chess.White, start.Add(61*time.Second), 0) {
+		t.Error("white not flagged one second after the deadline")
+	}
+	// La latence mesurée repousse la chute du drapeau, dans la limite de la compensation par coup
+	if ct.HasFlagged(chess.White, start.Add(60*time.Second+200*time.Millisecond), 300*time.Millisecond) {
+		t.Error("lag not compensated")
+	}
+	if !ct.HasFlagged(chess.White, start.Add(61*time.Second), 5*time.Second) {
+		t.Error("lag compensation above the per-move cap")
+	}
+	if ct.HasFlagged(chess.Black, start.Add(61*time.Second), 0) {
+		t.Error("black flagged while white's clock is running")
+	}
+}
@@ -1,36 +1,57 @@
 package service
 
 import (
+	"chess_backend/chess"
 	"fmt"
 	"sync"
 	"time"
 )
 
+// Pendule d'une partie : le temps restant est recalculé à partir d'horodatages monotones
+// à la réception de chaque coup, et la chute du drapeau est programmée par une seule échéance.
+// Les pendules ne sont diffusées qu'aux changements d'état ; les clients interpolent entre-temps.
 type ChessTimer struct {
-	room         *ChessGameRoom
-	ticker       *time.Ticker
-	stopChan     chan struct{}
-	mutex        sync.RWMutex
-	isRunning    bool
-	whiteSeconds int
-	blackSeconds int
-	roomID       string
+	room        *ChessGameRoom
+	mutex       sync.RWMutex
+	isRunning   bool
+	remaining   [2]time.Duration // temps de chaque camp au début du coup en cours
+	turn        chess.Color      // camp dont la pendule tourne
+	turnStart   time.Time        // début du coup en cours
+	deadline    *time.Timer      // chute du drapeau du camp au trait
+	timeControl TimeControl
+	movesPlayed [2]int             // coups joués par chaque camp, pour les cadences à plusieurs périodes
+	credited    [2][]time.Duration // temps crédité après chaque coup de chaque camp, repris en cas de reprise de coup
+	lagQuota    [2]time.Duration   // compensation du décalage réseau encore disponible
+	roomID      string
 }
 
+// État des pendules à l'instant ServerTime : le client décompte lui-même le temps du camp
+// au trait tant que IsRunning est vrai, en corrigeant ServerTime du décalage reçu dans clock_sync
 type TimerUpdate struct {
-	RoomID       string `json:"roomId"`
-	WhiteTime    int    `json:"whiteTime"`
-	BlackTime    int    `json:"blackTime"`
-	IsWhitesTurn bool   `json:"isWhitesTurn"`
+	RoomID       string      `json:"roomId"`
+	WhiteTime    int         `json:"whiteTime"` // secondes
+	BlackTime    int         `json:"blackTime"`
+	WhiteMs      int64       `json:"whiteMs"` // millisecondes
+	BlackMs      int64       `json:"blackMs"`
+	IsWhitesTurn bool        `json:"isWhitesTurn"`
+	IsRunning    bool        `json:"isRunning"`
+	ServerTime   int64       `json:"serverTime"` // millisecondes depuis l'epoch Unix
+	TimeControl  TimeControl `json:"timeControl"`
 }
 
-func NewChessTimer(room *ChessGameRoom, initialTimeMinutes int) *ChessTimer {
+func NewChessTimer(room *ChessGameRoom, timeControl TimeControl) *ChessTimer {
+	base := time.Duration(timeControl.Base()) * time.Second
+	turn := chess.Black
+	if room.IsWhitesTurn {
+		turn = chess.White
+	}
 	return &ChessTimer{
-		room:         room,
-		stopChan:     make(chan struct{}),
-		whiteSeconds: initialTimeMinutes * 60,
-		blackSeconds: initialTimeMinutes * 60,
-		roomID:       room.RoomID,
+		room:        room,
+		remaining:   [2]time.Duration{base, base},
+		lagQuota:    [2]time.Duration{lagQuotaInitial, lagQuotaInitial},
+		turn:        turn,
+		timeControl: timeControl,
+		roomID:      room.RoomID,
 	}
 }
 
@@ -42,207 +63,304 @@ func (ct *ChessTimer) Start() {
 	}
 
 	ct.isRunning = true
-	ct.ticker = time.NewTicker(1 * time.Second)
+	ct.turnStart = time.Now()
+	ct.scheduleDeadline()
 	ct.mutex.Unlock()
 
 	// Envoyer l'état initial immédiatement
 	ct.broadcastTimeUpdate()
+}
 
-	go ct.runTimer()
-}
-
-func (ct *ChessTimer) runTimer() {
-	for {
-		select {
-		case <-ct.ticker.C:
-			ct.mutex.Lock()
-
-			// Vérifier si la room existe toujours
-			if ct.room == nil {
-				ct.mutex.Unlock()
-				return
-			}
-
-			ct.room.mutex.RLock()
-			isWhitesTurn := ct.room.IsWhitesTurn
-			isGameOver := ct.room.IsGameOver
-			ct.room.mutex.RUnlock()
-
-			// Ne pas décrémenter si la partie est terminée
-			if isGameOver {
-				ct.mutex.Unlock()
-				continue
-			}
-
-			timeoutOccurred := false
-			var winner string
-
-			if isWhitesTurn {
-				if ct.whiteSeconds <= 0 {
-					timeoutOccurred = true
-					winner = "black"
-				} else {
-					ct.whiteSeconds--
-				}
-			} else {
-				if ct.blackSeconds <= 0 {
-					timeoutOccurred = true
-					winner = "white"
-				} else {
-					ct.blackSeconds--
-				}
-			}
-			ct.mutex.Unlock()
-
-			// Broadcast seulement si le timer est toujours actif
-			if ct.isRunning {
-				ct.broadcastTimeUpdate()
-			}
-
-			if timeoutOccurred {
-				ct.handleTimeOut(winner)
-				return
-			}
-
-		case <-ct.stopChan:
-			if ct.ticker != nil {
-				ct.ticker.Stop()
-			}
-			return
-		}
+// Passer la main après le coup reçu à l'instant at : le temps écoulé depuis le début du coup,
+// diminué de la latence lag du joueur dans la limite de son quota, lui est décompté,
+// puis il reçoit l'incrément de sa cadence.
+// Retourne le temps restant du joueur qui vient de jouer.
+func (ct *ChessTimer) SwitchTurn(at time.Time, lag time.Duration) time.Duration {
+	ct.mutex.Lock()
+	defer ct.mutex.Unlock()
+
+	mover := ct.turn
+	ct.turn = mover.Other()
+	if !ct.isRunning {
+		return ct.remaining[mover]
+	}
+
+	compensation := ct.lagAllowance(mover, lag)
+	ct.lagQuota[mover] -= compensation
+	elapsed := at.Sub(ct.turnStart) - compensation
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	ct.remaining[mover] -= ct.charge(mover, elapsed)
+	ct.lagQuota[mover] += lagQuotaGain
+	if ct.lagQuota[mover] > lagQuotaMax {
+		ct.lagQuota[mover] = lagQuotaMax
+	}
+	if ct.remaining[mover] <= 0 {
+		// Coup reçu après l'échéance : le drapeau est tombé
+		ct.remaining[mover] = 0
+		go ct.handleTimeOut(mover)
+	} else {
+		ct.completeMove(mover, elapsed)
 	}
+
+	ct.turnStart = at
+	ct.scheduleDeadline()
+	return ct.remaining[mover]
 }
 
-func (ct *ChessTimer) SwitchTurn() {
+// Redonner la main à turn après l'annulation de undone demi-coups (reprise de coup accordée) :
+// le temps écoulé est décompté, sans incrément, et le temps crédité après les coups annulés est repris
+func (ct *ChessTimer) Resync(turn chess.Color, at time.Time, undone int) {
 	ct.mutex.Lock()
 	defer ct.mutex.Unlock()
 
-	if !ct.isRunning {
-		return
+	if ct.isRunning {
+		elapsed := at.Sub(ct.turnStart)
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		ct.remaining[ct.turn] -= ct.charge(ct.turn, elapsed)
+		ct.turnStart = at
 	}
 
-	// Changer le tour dans le timer et dans la room de manière atomique
-	ct.room.mutex.Lock()
-	ct.room.IsWhitesTurn = !ct.room.IsWhitesTurn
-	ct.room.mutex.Unlock()
+	// Le dernier demi-coup a été joué par l'adversaire du camp au trait
+	mover := ct.turn.Other()
+	for ; undone > 0; undone-- {
+		ct.undoMove(mover)
+		mover = mover.Other()
+	}
+
+	ct.turn = turn
+	for _, c := range []chess.Color{chess.White, chess.Black} {
+		if ct.isRunning && ct.remaining[c] <= 0 {
+			ct.remaining[c] = 0
+			go ct.handleTimeOut(c)
+		}
+	}
+	ct.scheduleDeadline()
+}
+
+// Indique si le drapeau du camp c était tombé à l'envoi d'un coup reçu à l'instant at
+// sur une connexion de latence lag
+func (ct *ChessTimer) HasFlagged(c chess.Color, at time.Time, lag time.Duration) bool {
+	ct.mutex.RLock()
+	defer ct.mutex.RUnlock()
+	return ct.isRunning && ct.remainingAt(at.Add(-ct.lagAllowance(c, lag)))[c] <= 0
+}
 
-	// Créer une copie locale des valeurs nécessaires
-	update := TimerUpdate{
-		RoomID:       ct.room.RoomID,
-		WhiteTime:    ct.whiteSeconds,
-		BlackTime:    ct.blackSeconds,
-		IsWhitesTurn: !ct.room.IsWhitesTurn,
+// Compensation accordée au camp c pour une latence lag : plafonnée par coup et par son quota
+func (ct *ChessTimer) lagAllowance(c chess.Color, lag time.Duration) time.Duration {
+	if lag > maxLagAllowance {
+		lag = maxLagAllowance
 	}
+	if lag > ct.lagQuota[c] {
+		lag = ct.lagQuota[c]
+	}
+	if lag < 0 {
+		lag = 0
+	}
+	return lag
+}
 
-	// Broadcaster de manière asynchrone
-	go func() {
-		message := WebSocketMessage{
-			Type:    "time_update",
-			Content: string(mustJson(update)),
+// Temps décompté au camp c pour un coup de durée elapsed : en délai simple,
+// les premières secondes du coup ne sont pas décomptées
+func (ct *ChessTimer) charge(c chess.Color, elapsed time.Duration) time.Duration {
+	if ct.timeControl.Mode == ClockSimpleDelay {
+		elapsed -= ct.delay(c)
+		if elapsed < 0 {
+			elapsed = 0
 		}
-		ct.room.BroadcastMessage(message)
-	}()
+	}
+	return elapsed
 }
 
-func (ct *ChessTimer) handleTimeOut(winner string) {
-	// S'assurer que le timer est arrêté
-	ct.Stop()
+// Délai par coup du camp c en délai simple. Doit être appelée avec ct.mutex verrouillé.
+func (ct *ChessTimer) delay(c chess.Color) time.Duration {
+	if ct.timeControl.Mode != ClockSimpleDelay {
+		return 0
+	}
+	return time.Duration(ct.currentStage(c).Increment) * time.Second
+}
 
-	ct.room.mutex.Lock()
-	roomID := ct.room.RoomID
-	whiteUsername := ct.room.WhitePlayer.Username
-	blackUsername := ct.room.BlackPlayer.Username
+// Créditer le joueur c après un coup de durée elapsed : incrément Fischer, délai Bronstein,
+// puis temps de la période suivante s'il vient de terminer la sienne.
+// Doit être appelée avec ct.mutex verrouillé.
+func (ct *ChessTimer) completeMove(c chess.Color, elapsed time.Duration) {
+	stage := ct.timeControl.StageAt(ct.movesPlayed[c])
+	increment := time.Duration(ct.timeControl.Stages[stage].Increment) * time.Second
+	var credit time.Duration
+	switch ct.timeControl.Mode {
+	case ClockIncrement:
+		credit = increment
+	case ClockBronstein:
+		credit = increment
+		if elapsed < credit {
+			credit = elapsed
+		}
+	}
 
-	// Marquer la partie comme terminée
-	ct.room.IsGameOver = true
+	ct.movesPlayed[c]++
+	if next := ct.timeControl.StageAt(ct.movesPlayed[c]); next != stage {
+		credit += time.Duration(ct.timeControl.Stages[next].Base) * time.Second
+	}
+	ct.remaining[c] += credit
+	ct.credited[c] = append(ct.credited[c], credit)
+}
 
-	if winner == "white" {
-		ct.room.WinnerID = ct.room.WhitePlayer.ID
-	} else {
-		ct.room.WinnerID = ct.room.BlackPlayer.ID
+// Annuler le dernier coup crédité du joueur c : il revient à sa période précédente
+// et perd l'incrément reçu. Doit être appelée avec ct.mutex verrouillé.
+func (ct *ChessTimer) undoMove(c chess.Color) {
+	n := len(ct.credited[c])
+	if n == 0 {
+		return
 	}
+	ct.remaining[c] -= ct.credited[c][n-1]
+	ct.credited[c] = ct.credited[c][:n-1]
+	ct.movesPlayed[c]--
+}
 
-	// Copier les connexions nécessaires
-	connections := make(map[string]*SafeConn)
-	for username, conn := range ct.room.Connections {
-		connections[username] = conn
+// Période en cours du joueur c. Doit être appelée avec ct.mutex verrouillé.
+func (ct *ChessTimer) currentStage(c chess.Color) TimeStage {
+	return ct.timeControl.Stages[ct.timeControl.StageAt(ct.movesPlayed[c])]
+}
+
+// Temps restant de chaque camp à l'instant at. Doit être appelée avec ct.mutex verrouillé.
+func (ct *ChessTimer) remainingAt(at time.Time) [2]time.Duration {
+	remaining := ct.remaining
+	if ct.isRunning {
+		remaining[ct.turn] -= ct.charge(ct.turn, at.Sub(ct.turnStart))
+		if remaining[ct.turn] < 0 {
+			remaining[ct.turn] = 0
+		}
 	}
-	ct.room.mutex.Unlock()
+	return remaining
+}
 
-	// Envoyer le message de fin de partie
-	gameOver := map[string]interface{}{
-		"gameId":    roomID,
-		"winner":    winner,
-		"reason":    "timeout",
-		"whiteTime": formatTime(ct.whiteSeconds),
-		"blackTime": formatTime(ct.blackSeconds),
-		"winnerId":  ct.room.WinnerID,
+// Programmer la chute du drapeau du camp au trait. Doit être appelée avec ct.mutex verrouillé.
+func (ct *ChessTimer) scheduleDeadline() {
+	if ct.deadline != nil {
+		ct.deadline.Stop()
+	}
+	if !ct.isRunning {
+		return
 	}
 
-	// Envoyer aux deux joueurs
-	for _, conn := range connections {
-		conn.WriteJSON(WebSocketMessage{
-			Type:    "game_over",
-			Content: string(mustJson(gameOver)),
-		})
+	// Laisser au coup en route le temps d'arriver : le drapeau tombe après la compensation maximale
+	turn := ct.turn
+	left := ct.remaining[turn] + ct.delay(turn) + ct.lagAllowance(turn, maxLagAllowance) - time.Since(ct.turnStart)
+	ct.deadline = time.AfterFunc(left, func() {
+		ct.onDeadline(turn)
+	})
+}
+
+func (ct *ChessTimer) onDeadline(turn chess.Color) {
+	ct.mutex.Lock()
+	// Échéance périmée : le joueur a joué entre-temps
+	if !ct.isRunning || ct.turn != turn {
+		ct.mutex.Unlock()
+		return
+	}
+	if ct.remainingAt(time.Now().Add(-ct.lagAllowance(turn, maxLagAllowance)))[turn] > 0 {
+		ct.scheduleDeadline()
+		ct.mutex.Unlock()
+		return
 	}
+	ct.mutex.Unlock()
 
-	// Nettoyer la room après un court délai
-	go func() {
-		time.Sleep(200 * time.Millisecond)
-		if ct.room.onlineManager != nil {
-			// Nettoyer uniquement les joueurs de cette room
-			ct.room.onlineManager.cleanupPlayerFromPublicQueue(whiteUsername)
-			ct.room.onlineManager.cleanupPlayerFromPublicQueue(blackUsername)
+	ct.handleTimeOut(turn)
+}
 
-			// Mettre à jour le statut des joueurs
-			ct.room.onlineManager.userStore.UpdateUserRoomStatus(whiteUsername, false)
-			ct.room.onlineManager.userStore.UpdateUserRoomStatus(blackUsername, false)
+func (ct *ChessTimer) handleTimeOut(flagged chess.Color) {
+	// S'assurer que le timer est arrêté
+	ct.Stop()
 
-			// Supprimer uniquement cette room
-			ct.room.onlineManager.roomManager.RemoveSpecificRoom(roomID)
-		}
-	}()
+	// Nulle plutôt que victoire si l'adversaire n'a pas de quoi mater
+	ct.room.mutex.RLock()
+	isGameOver := ct.room.IsGameOver
+	outcome := ct.room.game.Position().TimeoutOutcome(flagged)
+	ct.room.mutex.RUnlock()
+
+	// La partie s'est terminée sur l'échiquier avant la chute du drapeau
+	if isGameOver {
+		return
+	}
+	ct.room.finishGame(outcome)
 }
 
+// Temps restant de chaque camp
+func (ct *ChessTimer) Remaining() (time.Duration, time.Duration) {
+	ct.mutex.RLock()
+	defer ct.mutex.RUnlock()
+	remaining := ct.remainingAt(time.Now())
+	return remaining[chess.White], remaining[chess.Black]
+}
+
+// Arrêter la pendule en figeant le temps restant, puis diffuser les pendules arrêtées
 func (ct *ChessTimer) Stop() {
 	ct.mutex.Lock()
-	defer ct.mutex.Unlock()
-
+	wasRunning := ct.isRunning
 	if ct.isRunning {
-		close(ct.stopChan)
-		ct.ticker.Stop()
+		now := time.Now()
+		ct.remaining = ct.remainingAt(now)
+		ct.turnStart = now
+		if ct.deadline != nil {
+			ct.deadline.Stop()
+		}
 		ct.isRunning = false
 	}
+	ct.mutex.Unlock()
+
+	// Diffusé avant de rendre la main : l'arrêt des pendules précède game_over dans chaque file
+	if wasRunning {
+		ct.broadcastTimeUpdate()
+	}
 }
 
-func (ct *ChessTimer) broadcastTimeUpdate() {
+// État des pendules envoyé aux clients
+func (ct *ChessTimer) update() TimerUpdate {
 	ct.mutex.RLock()
 	defer ct.mutex.RUnlock()
 
+	now := time.Now()
+	remaining := ct.remainingAt(now)
+	return TimerUpdate{
+		RoomID:       ct.roomID,
+		WhiteTime:    int(remaining[chess.White] / time.Second),
+		BlackTime:    int(remaining[chess.Black] / time.Second),
+		WhiteMs:      remaining[chess.White].Milliseconds(),
+		BlackMs:      remaining[chess.Black].Milliseconds(),
+		IsWhitesTurn: ct.turn == chess.White,
+		IsRunning:    ct.isRunning,
+		ServerTime:   now.UnixMilli(),
+		TimeControl:  ct.timeControl,
+	}
+}
+
+// État des pendules de la room pour game_start et game_sync (nil sans pendule)
+func (room *ChessGameRoom) clockState() *TimerUpdate {
+	if room.Timer == nil {
+		return nil
+	}
+	update := room.Timer.update()
+	return &update
+}
+
+func (ct *ChessTimer) broadcastTimeUpdate() {
 	// Vérifier si la room existe toujours
 	if ct.room == nil {
 		return
 	}
 
-	ct.room.mutex.RLock()
-	isWhitesTurn := ct.room.IsWhitesTurn
-	roomID := ct.room.RoomID
-	ct.room.mutex.RUnlock()
-
-	update := TimerUpdate{
-		RoomID:       roomID,
-		WhiteTime:    ct.whiteSeconds,
-		BlackTime:    ct.blackSeconds,
-		IsWhitesTurn: isWhitesTurn,
-	}
+	ct.room.BroadcastMessage(ct.timeUpdateMessage())
+}
 
-	message := WebSocketMessage{
+// Message time_update avec l'état actuel des pendules
+func (ct *ChessTimer) timeUpdateMessage() WebSocketMessage {
+	return WebSocketMessage{
 		Type:    "time_update",
-		Content: string(mustJson(update)),
+		Content: string(mustJson(ct.update())),
 	}
-
-	ct.room.BroadcastMessage(message)
 }
 
 // Fonction utilitaire pour formater le temps en string "MM:SS"
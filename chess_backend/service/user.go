@@ -147,6 +147,11 @@ func CreateUserHandler(userStore *UserStore) http.HandlerFunc {
 			return
 		}
 
+		if isBotUsername(userInput.UserName) {
+			http.Error(w, "Username is reserved", http.StatusConflict)
+			return
+		}
+
 		_, err := userStore.GetUser(userInput.UserName)
 		if err == nil {
 
@@ -234,6 +239,7 @@ func DisconnectUserHandler(userStore *UserStore, onlineUsersManager *OnlineUsers
 		// Fermer la connexion WebSocket si elle existe
 		onlineUsersManager.mutex.Lock()
 		if conn, exists := onlineUsersManager.connections[username]; exists {
+			conn.Close()
 			conn.conn.Close()
 			delete(onlineUsersManager.connections, username)
 		}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"chess_backend/chess"
+	"fmt"
+	"math/rand"
+)
+
+// Variantes proposées dans les invitations et la file publique
+const (
+	VariantStandard      = "standard"
+	VariantChess960      = "chess960"
+	VariantKingOfTheHill = "kingofthehill"
+	VariantThreeCheck    = "threecheck"
+	VariantAtomic        = "atomic"
+	VariantRacingKings   = "racingkings"
+	VariantCrazyhouse    = "crazyhouse"
+	VariantBughouse      = "bughouse"
+	VariantFogOfWar      = "fogofwar"
+)
+
+// Nom de la variante pour la balise PGN Variant
+var pgnVariantNames = map[string]string{
+	VariantChess960:      "Chess960",
+	VariantKingOfTheHill: "King of the Hill",
+	VariantThreeCheck:    "Three-check",
+	VariantAtomic:        "Atomic",
+	VariantRacingKings:   "Racing Kings",
+	VariantCrazyhouse:    "Crazyhouse",
+	VariantBughouse:      "Bughouse",
+	VariantFogOfWar:      "Fog of War",
+}
+
+// Nom de variante normalisé ("" vaut partie classique)
+func normalizeVariant(variant string) (string, error) {
+	switch variant {
+	case "":
+		return VariantStandard, nil
+	case VariantChess960:
+		return VariantChess960, nil
+	case VariantBughouse:
+		return "", fmt.Errorf("bughouse games start from a team invitation")
+	}
+	if _, found := chess.VariantByName(variant); found {
+		return variant, nil
+	}
+	return "", fmt.Errorf("unknown variant %q", variant)
+}
+
+// Règles du moteur pour une variante ; le Chess960 suit les règles classiques
+func variantRules(variant string) chess.Variant {
+	if rules, found := chess.VariantByName(variant); found {
+		return rules
+	}
+	return chess.Standard{}
+}
+
+// Partie de départ d'une room : FEN imposée ou position initiale de la variante
+func newVariantGame(variant, fen string) (*chess.Game, error) {
+	if variant == VariantChess960 {
+		if fen == "" {
+			fen, _ = chess.Chess960FEN(rand.Intn(960))
+		}
+		return chess.NewChess960Game(fen)
+	}
+	return chess.NewVariantGame(variantRules(variant), fen)
+}
+
+// Valider une FEN de départ pour la variante et la retourner sous forme canonique
+func validateVariantFEN(variant, fen string) (string, error) {
+	position, err := chess.ValidateVariantFEN(variantRules(variant), fen)
+	if err != nil {
+		return "", err
+	}
+	// Hors Chess960, un droit de roque suppose le roi en e1/e8 et la tour sur la colonne a ou h
+	if variant != VariantChess960 && position.IsChess960() {
+		return "", fmt.Errorf("%w: castling rights require the king on the e-file and rooks on the a or h file",
+			chess.ErrInvalidFEN)
+	}
+	return position.FEN(), nil
+}
+
+// Nom de la variante pour la balise PGN Variant ("" pour une partie classique)
+func pgnVariantName(variant string) string {
+	return pgnVariantNames[variant]
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -18,10 +19,11 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func NewOnlineUsersManager(userStore *UserStore) *OnlineUsersManager {
+func NewOnlineUsersManager(userStore *UserStore, gameArchive *GameArchive) *OnlineUsersManager {
 	manager := &OnlineUsersManager{
 		connections: make(map[string]*SafeConn),
 		userStore:   userStore,
+		gameArchive: gameArchive,
 		publicQueue: &PublicGameQueue{
 			waitingPlayers: make(map[string]*QueuedPlayer),
 		},
@@ -29,6 +31,11 @@ func NewOnlineUsersManager(userStore *UserStore) *OnlineUsersManager {
 	
 	manager.roomManager = NewRoomManager(manager)
 	manager.tempRoomManager = NewTemporaryRoomManager()
+	manager.bughouse = NewBughouseManager()
+	manager.uciPool = setupUCIPool()
+	manager.analyzer = NewAnalyzer(manager.uciPool)
+	manager.reviewer = NewReviewer(manager)
+	manager.lagMonitor = NewLagMonitor()
 	return manager
 }
 
@@ -57,10 +64,14 @@ func (m *OnlineUsersManager) HandleConnection(w http.ResponseWriter, r *http.Req
 
 	safeConn := NewSafeConn(conn)
 
-	// Ajouter la connexion
+	// Ajouter la connexion ; celle qu'elle remplace n'écrit plus rien
 	m.mutex.Lock()
+	previous, reconnected := m.connections[username]
 	m.connections[username] = safeConn
 	m.mutex.Unlock()
+	if reconnected {
+		previous.Close()
+	}
 
 	// Mettre à jour le statut en ligne
 	m.userStore.UpdateUserOnlineStatus(username, true, false)
@@ -69,12 +80,18 @@ func (m *OnlineUsersManager) HandleConnection(w http.ResponseWriter, r *http.Req
 	m.broadcastOnlineUsers()
 
 	// Gestion de la connexion
-	go m.handleClientConnection(username, conn)
+	go m.handleClientConnection(username, safeConn)
 
 }
 
 // Gérer les messages du client
-func (m *OnlineUsersManager) handleClientConnection(username string, conn *websocket.Conn) {
+func (m *OnlineUsersManager) handleClientConnection(username string, safeConn *SafeConn) {
+	conn := safeConn.conn
+
+	// Mesurer la latence de la connexion tant qu'elle reste ouverte
+	done := make(chan struct{})
+	defer close(done)
+	go m.runClockSync(username, done)
 
 	defer func() {
 		// Trouver et nettoyer la room si l'utilisateur y était
@@ -98,10 +115,16 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 			m.handleInvitation(invitation)
 		}
 
-		// Nettoyer la connexion
+		// Annuler les invitations bughouse en attente du joueur
+		m.cancelBughouseInvitesFor(username)
+
+		// Nettoyer la connexion, sauf si une reconnexion l'a déjà remplacée
 		m.mutex.Lock()
-		delete(m.connections, username)
+		if m.connections[username] == safeConn {
+			delete(m.connections, username)
+		}
 		m.mutex.Unlock()
+		safeConn.Close()
 
 		// Mettre à jour le statut hors ligne
 		m.userStore.UpdateUserOnlineStatus(username, false, false)
@@ -120,6 +143,7 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 			log.Printf("WebSocket read error for %s: %v", username, err)
 			break
 		}
+		receivedAt := time.Now()
 
 		var wg sync.WaitGroup
 		wg.Add(1)
@@ -131,7 +155,7 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 			case "request_online_users":
 
 				onlineUsers := m.getCurrentOnlineUsers()
-				conn.WriteJSON(WebSocketMessage{
+				safeConn.Send(WebSocketMessage{
 					Type:    "online_users",
 					Content: string(mustJson(onlineUsers)),
 				})
@@ -142,6 +166,11 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 					log.Printf("Error parsing invitation: %v", err)
 					return
 				}
+				// On ne quitte que sa propre partie, quel que soit le nom envoyé
+				if msg.Type == string(RoomLeave) {
+					invitation.Type = RoomLeave
+					invitation.FromUsername = username
+				}
 
 				if err := m.handleInvitation(invitation); err != nil {
 					log.Printf("Failed to process invitation: %v", err)
@@ -149,17 +178,10 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 				m.broadcastOnlineUsers()
 
 			case "leave_room":
-				var leaveRequest struct {
-					Username string `json:"username"`
-				}
-				if err := json.Unmarshal([]byte(msg.Content), &leaveRequest); err != nil {
-					log.Printf("Error parsing leave room request: %v", err)
-					return
-				}
-
+				// Le nom éventuellement envoyé est ignoré : seul l'utilisateur de la connexion quitte sa room
 				m.cleanupPlayerFromPublicQueue(username)
 
-				_, err := m.RemoveUserFromRoom(leaveRequest.Username)
+				_, err := m.RemoveUserFromRoom(username)
 				if err != nil {
 					log.Printf("Error removing user from room: %v", err)
 					return
@@ -170,15 +192,7 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 
 				// moves
 			case "game_move":
-				var moveData struct {
-					GameID       string      `json:"gameId"`
-					FromUserID   string      `json:"fromUserId"`
-					ToUserID     string      `json:"toUserId"`
-					ToUsername   string      `json:"toUsername"`
-					Move         interface{} `json:"move"`
-					FEN          string      `json:"fen"`
-					IsWhitesTurn bool        `json:"isWhitesTurn"`
-				}
+				var moveData MoveData
 
 				if err := json.Unmarshal([]byte(msg.Content), &moveData); err != nil {
 					log.Printf("Error parsing move data: %v", err)
@@ -196,10 +210,18 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 				}
 
 				// Envoyer le mouvement avec la nouvelle méthode
-				if err := room.SendMove(moveData); err != nil {
+				if err := room.SendMove(username, moveData, receivedAt); err != nil {
 					log.Printf("Error sending move: %v", err)
-					// Optionnel: notifier le joueur de l'échec
-					conn.WriteJSON(WebSocketMessage{
+					// Notifier le joueur de l'échec avec la position faisant foi
+					var moveErr *MoveError
+					if errors.As(err, &moveErr) {
+						safeConn.Send(WebSocketMessage{
+							Type:    "move_error",
+							Content: string(mustJson(moveErr)),
+						})
+						return
+					}
+					safeConn.Send(WebSocketMessage{
 						Type: "move_error",
 						Content: string(mustJson(map[string]string{
 							"error": err.Error(),
@@ -210,10 +232,7 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 
 			case "game_over_checkmate":
 				var gameOverData struct {
-					GameID   string `json:"gameId"`
-					Winner   string `json:"winner"`
-					Reason   string `json:"reason"`
-					WinnerID string `json:"winnerId"`
+					GameID string `json:"gameId"`
 				}
 
 				if err := json.Unmarshal([]byte(msg.Content), &gameOverData); err != nil {
@@ -231,60 +250,97 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 					return
 				}
 
-				// Marquer la partie comme terminée
-				room.mutex.Lock()
-				room.IsGameOver = true
-				room.WinnerID = gameOverData.WinnerID
-				connections := make(map[string]*SafeConn, len(room.Connections))
-				for k, v := range room.Connections {
-					connections[k] = v
-				}
-				room.mutex.Unlock()
+				// Le message du client n'est qu'un indice : seul l'état du plateau fait foi
+				room.mutex.RLock()
+				outcome, over := room.gameOutcome()
+				room.mutex.RUnlock()
 
-				// Arrêter le timer immédiatement
-				if room.Timer != nil {
-					room.Timer.Stop()
+				if !over {
+					log.Printf("Ignoring game over claim from %s in room %s: position is not terminal",
+						username, gameOverData.GameID)
+					return
 				}
 
-				// Envoyer le message de fin de partie à tous les joueurs
-				gameOverMessage := WebSocketMessage{
-					Type:    "game_over_checkmate",
-					Content: msg.Content,
-				}
+				room.finishGame(outcome)
 
-				for username, conn := range connections {
-					if err := conn.WriteJSON(gameOverMessage); err != nil {
-						log.Printf("Error sending game over notification to %s: %v", username, err)
-					}
-				}
+			case DrawOffer, DrawAccept, DrawDecline:
+				m.handleDrawMessage(username, msg)
 
-				// Nettoyer la room après un délai
-				go func() {
-					time.Sleep(2 * time.Second)
+			case Resign:
+				m.handleResign(username, msg)
 
-					// Mettre à jour le statut des joueurs
-					for username := range connections {
-						m.userStore.UpdateUserRoomStatus(username, false)
-					}
+			case TakebackRequest, TakebackAccept, TakebackDecline:
+				m.handleTakebackMessage(username, msg)
 
-					// Supprimer la room
-					m.roomManager.RemoveSpecificRoom(gameOverData.GameID)
+			case GameSync:
+				// Renvoyer l'état complet de la partie (reconnexion, spectateurs)
+				var syncRequest struct {
+					GameID string `json:"gameId"`
+				}
+				if err := json.Unmarshal([]byte(msg.Content), &syncRequest); err != nil {
+					log.Printf("Error parsing sync request: %v", err)
+					return
+				}
 
-					// Broadcast la mise à jour
-					m.broadcastOnlineUsers()
-				}()
+				room, exists := m.roomManager.GetRoom(syncRequest.GameID)
+				if !exists {
+					log.Printf("Room not found: %s", syncRequest.GameID)
+					return
+				}
+				safeConn.Send(room.syncMessageFor(username))
 
 			case PublicGameRequest:
 				user, err := m.userStore.GetUser(username)
 				if err != nil {
 					return
 				}
-				safeConn := NewSafeConn(conn)
-				m.handlePublicGameRequest(username, user.ID, safeConn)
+				// Variante et cadence souhaitées, facultatives
+				var request struct {
+					Variant     string `json:"variant"`
+					TimeControl string `json:"time_control"`
+				}
+				json.Unmarshal([]byte(msg.Content), &request)
+				variant, err := normalizeVariant(request.Variant)
+				var timeControl TimeControl
+				if err == nil {
+					timeControl, err = ParseTimeControl(request.TimeControl)
+				}
+				if err != nil {
+					safeConn.Send(WebSocketMessage{
+						Type: "error",
+						Content: string(mustJson(map[string]string{
+							"message": err.Error(),
+						})),
+					})
+					return
+				}
+				m.mutex.RLock()
+				safeConn, connected := m.connections[username]
+				m.mutex.RUnlock()
+				if !connected {
+					return
+				}
+				m.handlePublicGameRequest(username, user.ID, variant, timeControl, safeConn)
 
 			case PublicQueueLeave:
 				m.handlePublicQueueLeave(username)
 
+			case BughouseInvite, BughouseAccept, BughouseReject:
+				m.handleBughouseMessage(username, msg)
+
+			case AnalysisRequestMessage:
+				// Une analyse peut durer plusieurs secondes : ne pas bloquer la lecture des messages
+				go m.handleAnalysisRequest(username, msg)
+
+			case PartnerChat:
+				m.handlePartnerChat(username, msg)
+
+			case ReviewSubscribe:
+				m.handleReviewSubscribe(username, msg)
+
+			case ClockPong:
+				m.handleClockPong(username, msg, receivedAt)
+
 			default:
 				log.Printf("Unhandled message type: %s", msg.Type)
 				m.broadcastOnlineUsers()
@@ -296,7 +352,29 @@ func (m *OnlineUsersManager) handleClientConnection(username string, conn *webso
 	}
 }
 
+// Signaler à l'expéditeur une invitation refusée par le serveur
+func (m *OnlineUsersManager) sendInvitationError(invitation InvitationMessage, err error) {
+	m.mutex.RLock()
+	conn, exists := m.connections[invitation.FromUsername]
+	m.mutex.RUnlock()
+	if !exists {
+		return
+	}
+	conn.Send(WebSocketMessage{
+		Type: "invitation_error",
+		Content: string(mustJson(map[string]string{
+			"roomId":     invitation.RoomID,
+			"toUsername": invitation.ToUsername,
+			"error":      err.Error(),
+		})),
+	})
+}
+
 func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) error {
+	if invitation.Type == InvitationSend && m.isBotAvailable(invitation.ToUsername) {
+		return m.startBotGame(invitation)
+	}
+
 	m.mutex.RLock()
 	_, fromExists := m.connections[invitation.FromUsername]
 	toConn, toExists := m.connections[invitation.ToUsername]
@@ -336,6 +414,30 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 			invitation.RoomID = GenerateUniqueID()
 		}
 
+		variant, err := normalizeVariant(invitation.Variant)
+		if err != nil {
+			m.sendInvitationError(invitation, err)
+			return err
+		}
+		invitation.Variant = variant
+
+		timeControl, err := ParseTimeControl(invitation.TimeControl)
+		if err != nil {
+			m.sendInvitationError(invitation, err)
+			return err
+		}
+		invitation.TimeControl = timeControl.String()
+
+		// Valider la position de départ personnalisée avant de transmettre l'invitation
+		if invitation.FEN != "" {
+			fen, err := validateVariantFEN(invitation.Variant, invitation.FEN)
+			if err != nil {
+				m.sendInvitationError(invitation, err)
+				return err
+			}
+			invitation.FEN = fen
+		}
+
 		// Créer le timer
 		timeout := NewInvitationTimeout(invitation.RoomID, 20*time.Second, func() {
 			// Fonction appelée quand le timeout expire
@@ -355,10 +457,10 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 
 				// Envoyer le message aux deux joueurs
 				if fromConn, exists := m.connections[tempRoom.WhitePlayer.Username]; exists {
-					fromConn.WriteJSON(timeoutMsg)
+					fromConn.Send(timeoutMsg)
 				}
 				if toConn, exists := m.connections[tempRoom.BlackPlayer.Username]; exists {
-					toConn.WriteJSON(timeoutMsg)
+					toConn.Send(timeoutMsg)
 				}
 
 				// Nettoyer la room temporaire
@@ -372,7 +474,7 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 
 		// Envoyer l'invitation
 		if toConn, exists := m.connections[invitation.ToUsername]; exists {
-			err := toConn.WriteJSON(WebSocketMessage{
+			err := toConn.Send(WebSocketMessage{
 				Type:    "invitation",
 				Content: string(mustJson(invitation)),
 			})
@@ -384,10 +486,16 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 
 	case InvitationAccept:
 		// Récupérer et nettoyer la room temporaire
-		if _, exists := m.tempRoomManager.GetTempRoom(invitation.RoomID); exists {
+		if tempRoom, exists := m.tempRoomManager.GetTempRoom(invitation.RoomID); exists {
 
 			m.tempRoomManager.RemoveTempRoom(invitation.RoomID)
 
+			// La position de départ est celle de l'invitation envoyée, pas celle de la réponse
+			invitation.FEN = tempRoom.FEN
+			invitation.Variant = tempRoom.Variant
+			invitation.TimeControl = tempRoom.TimeControl
+			invitation.Rated = tempRoom.Rated
+
 			// Créer la nouvelle room de jeu
 			gameRoom := m.roomManager.CreateRoom(invitation)
 
@@ -395,12 +503,8 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 			m.userStore.UpdateUserRoomStatus(invitation.FromUsername, true)
 			m.userStore.UpdateUserRoomStatus(invitation.ToUsername, true)
 
-			// Initialiser l'état du jeu
-			gameRoom.PositionFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
-			gameRoom.IsWhitesTurn = true
-			gameRoom.IsGameOver = false
-
-			// Préparer les états de jeu pour les deux joueurs
+			// Préparer les états de jeu pour les deux joueurs (position fixée par CreateRoom)
+			gameRoom.mutex.RLock()
 			baseGameState := map[string]interface{}{
 				"gameId":         invitation.RoomID,
 				"gameCreatorUid": invitation.FromUserID,
@@ -411,17 +515,21 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 				"isWhitesTurn":   gameRoom.IsWhitesTurn,
 				"isGameOver":     gameRoom.IsGameOver,
 				"moves":          gameRoom.Moves,
+				"variant":        gameRoom.Variant,
+				"timeControl":    gameRoom.TimeControl,
+				"clock":          gameRoom.clockState(),
 			}
+			gameRoom.mutex.RUnlock()
 
 			// États spécifiques pour chaque joueur
-			creatorGameState := copyAndAddUserInfo(baseGameState, invitation.FromUserID, invitation.ToUsername)
-			inviteeGameState := copyAndAddUserInfo(baseGameState, invitation.ToUserID, invitation.FromUsername)
+			creatorGameState := gameRoom.projectState(copyAndAddUserInfo(baseGameState, invitation.FromUserID, invitation.ToUsername), invitation.FromUsername)
+			inviteeGameState := gameRoom.projectState(copyAndAddUserInfo(baseGameState, invitation.ToUserID, invitation.FromUsername), invitation.ToUsername)
 
 			// Envoyer les messages aux joueurs
 			fromConn, fromExists := m.connections[invitation.FromUsername]
 			if fromExists {
 				gameRoom.AddConnection(invitation.FromUsername, fromConn)
-				fromConn.WriteJSON(WebSocketMessage{
+				fromConn.Send(WebSocketMessage{
 					Type:    "game_start",
 					Content: string(mustJson(creatorGameState)),
 				})
@@ -429,7 +537,7 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 
 			if toExists {
 				gameRoom.AddConnection(invitation.ToUsername, toConn)
-				toConn.WriteJSON(WebSocketMessage{
+				toConn.Send(WebSocketMessage{
 					Type:    "game_start",
 					Content: string(mustJson(inviteeGameState)),
 				})
@@ -442,7 +550,7 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 		fromConn, fromExists := m.connections[invitation.ToUsername]
 
 		if fromExists {
-			err := fromConn.WriteJSON(WebSocketMessage{
+			err := fromConn.Send(WebSocketMessage{
 				Type:    "invitation_rejected",
 				Content: string(mustJson(invitation)),
 			})
@@ -460,7 +568,7 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 
 			fromConn, fromExists := m.connections[invitation.ToUsername]
 			if fromExists {
-				err := fromConn.WriteJSON(WebSocketMessage{
+				err := fromConn.Send(WebSocketMessage{
 					Type:    "invitation_cancelled",
 					Content: string(mustJson(invitation)),
 				})
@@ -483,18 +591,28 @@ func (m *OnlineUsersManager) handleInvitation(invitation InvitationMessage) erro
 			room.Timer.Stop()
 		}
 
+		// Quitter une partie en cours compte comme une défaite
+		closeContent := map[string]string{
+			"room_id": invitation.RoomID,
+			"reason":  "opponent_left",
+		}
+		if outcome, forfeited := room.forfeit(invitation.FromUsername); forfeited {
+			room.mutex.RLock()
+			closeContent["winnerId"] = room.WinnerID
+			room.mutex.RUnlock()
+			closeContent["result"] = outcome.Result()
+			closeContent["termination"] = string(outcome.Termination)
+		}
+
 		// Notifier l'autre joueur avant de supprimer la room
 		otherUsername, found := room.GetOtherPlayer(invitation.FromUsername)
 		if found {
 			if conn, exists := room.Connections[otherUsername]; exists {
 				closeMsg := WebSocketMessage{
-					Type: "room_closed",
-					Content: string(mustJson(map[string]string{
-						"room_id": invitation.RoomID,
-						"reason":  "opponent_left",
-					})),
+					Type:    "room_closed",
+					Content: string(mustJson(closeContent)),
 				}
-				conn.WriteJSON(closeMsg)
+				conn.Send(closeMsg)
 			}
 		}
 
@@ -557,7 +675,7 @@ func (m *OnlineUsersManager) broadcastOnlineUsers() {
 	}
 
 	for _, conn := range connections {
-		if err := conn.WriteJSON(message); err != nil {
+		if err := conn.Send(message); err != nil {
 			log.Printf("Error broadcasting: %v", err)
 		}
 	}
@@ -588,8 +706,9 @@ func (m *OnlineUsersManager) getCurrentOnlineUsers() []OnlineUser {
 	}
 	m.publicQueue.mutex.RUnlock()
 
-	// Ne garder que les utilisateurs qui ne sont ni dans des rooms ni dans la file d'attente
-	onlineUsers := make([]OnlineUser, 0)
+	// Ne garder que les utilisateurs qui ne sont ni dans des rooms ni dans la file d'attente ;
+	// l'ordinateur est toujours disponible
+	onlineUsers := m.botUsers()
 	for username := range connections {
 		// Vérifier si l'utilisateur n'est ni dans une room ni dans la file d'attente
 		if !usersInRooms[username] && !usersInPublicQueue[username] {
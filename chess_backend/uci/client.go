@@ -0,0 +1,345 @@
+package uci
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrEngineExited = errors.New("uci engine exited")
+	ErrTimeout      = errors.New("uci engine did not answer in time")
+)
+
+// Délais d'attente des réponses du moteur
+const (
+	handshakeTimeout = 10 * time.Second
+	readyTimeout     = 5 * time.Second
+	stopGrace        = 2 * time.Second
+	quitGrace        = time.Second
+)
+
+// Position et paramètres d'une commande "go"
+type SearchRequest struct {
+	GameID   string   // une partie différente de la précédente déclenche "ucinewgame"
+	FEN      string   // position de départ ("" pour la position initiale)
+	Moves    []string // coups joués depuis la position de départ, en notation UCI
+	Chess960 bool
+
+	WTime, BTime time.Duration
+	WInc, BInc   time.Duration
+	MoveTime     time.Duration
+	Depth        int
+	Nodes        uint64
+	MultiPV      int
+
+	// Appelée pour chaque ligne "info" portant une évaluation
+	OnInfo func(Info)
+}
+
+// Résultat d'une commande "go"
+type SearchResult struct {
+	BestMove string `json:"bestMove"`
+	Ponder   string `json:"ponder,omitempty"`
+	// Dernière évaluation de chaque variante, par ordre de multipv
+	Lines []Info `json:"lines"`
+}
+
+// Client UCI : un processus moteur piloté par son entrée et sa sortie standard.
+// Un client ne sert qu'une recherche à la fois.
+type Client struct {
+	Name   string
+	Author string
+
+	path     string
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	lines    chan string   // sortie du moteur, fermée quand le processus se termine
+	exited   chan struct{} // fermé après la fin du processus
+	mutex    sync.Mutex
+	lastGame string
+	multiPV  int
+	chess960 bool
+}
+
+// Lancer le moteur, faire la poignée de main "uci" et appliquer les options
+func Start(path string, options map[string]string) (*Client, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start uci engine %s: %w", path, err)
+	}
+
+	c := &Client{
+		path:    path,
+		cmd:     cmd,
+		stdin:   stdin,
+		lines:   make(chan string, 256),
+		exited:  make(chan struct{}),
+		multiPV: 1,
+	}
+	go c.readOutput(stdout)
+
+	if err := c.handshake(options); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) readOutput(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		c.lines <- scanner.Text()
+	}
+	close(c.lines)
+	c.cmd.Wait()
+	close(c.exited)
+}
+
+func (c *Client) handshake(options map[string]string) error {
+	if err := c.send("uci"); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(handshakeTimeout)
+	for {
+		line, err := c.readLine(deadline)
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(line, "id name "):
+			c.Name = strings.TrimPrefix(line, "id name ")
+		case strings.HasPrefix(line, "id author "):
+			c.Author = strings.TrimPrefix(line, "id author ")
+		case line == "uciok":
+			for name, value := range options {
+				if err := c.send("setoption name %s value %s", name, value); err != nil {
+					return err
+				}
+			}
+			return c.isReady()
+		}
+	}
+}
+
+func (c *Client) send(format string, args ...interface{}) error {
+	if _, err := fmt.Fprintf(c.stdin, format+"\n", args...); err != nil {
+		return fmt.Errorf("%w: %v", ErrEngineExited, err)
+	}
+	return nil
+}
+
+// Lire la prochaine ligne du moteur avant l'échéance
+func (c *Client) readLine(deadline time.Time) (string, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case line, ok := <-c.lines:
+		if !ok {
+			return "", ErrEngineExited
+		}
+		return line, nil
+	case <-timer.C:
+		return "", ErrTimeout
+	}
+}
+
+// Attendre que le moteur ait traité toutes les commandes envoyées
+func (c *Client) isReady() error {
+	if err := c.send("isready"); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		line, err := c.readLine(deadline)
+		if err != nil {
+			return err
+		}
+		if line == "readyok" {
+			return nil
+		}
+	}
+}
+
+// Indique si le processus du moteur tourne encore
+func (c *Client) Alive() bool {
+	select {
+	case <-c.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// Chercher un coup ; l'annulation du contexte envoie "stop" et attend le "bestmove"
+func (c *Client) Search(ctx context.Context, req SearchRequest) (SearchResult, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.prepare(req); err != nil {
+		return SearchResult{}, err
+	}
+
+	position := "position startpos"
+	if req.FEN != "" {
+		position = "position fen " + req.FEN
+	}
+	if len(req.Moves) > 0 {
+		position += " moves " + strings.Join(req.Moves, " ")
+	}
+	if err := c.send("%s", position); err != nil {
+		return SearchResult{}, err
+	}
+	if err := c.send("%s", goCommand(req)); err != nil {
+		return SearchResult{}, err
+	}
+
+	var result SearchResult
+	stopped := false
+	var stopDeadline time.Time
+	for {
+		var line string
+		var ok bool
+		if stopped {
+			var err error
+			if line, err = c.readLine(stopDeadline); err != nil {
+				// Le moteur ne répond plus : il sera relancé par le pool
+				c.kill()
+				return result, err
+			}
+			ok = true
+		} else {
+			select {
+			case line, ok = <-c.lines:
+			case <-ctx.Done():
+				stopped = true
+				stopDeadline = time.Now().Add(stopGrace)
+				if err := c.send("stop"); err != nil {
+					return result, err
+				}
+				continue
+			}
+		}
+		if !ok {
+			return result, ErrEngineExited
+		}
+
+		if info, found := ParseInfo(line); found {
+			result.Lines = setLine(result.Lines, info)
+			if req.OnInfo != nil {
+				req.OnInfo(info)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "bestmove") {
+			result.BestMove, result.Ponder = parseBestMove(line)
+			if result.BestMove == "" || result.BestMove == "(none)" {
+				return result, fmt.Errorf("uci engine returned no move")
+			}
+			return result, nil
+		}
+	}
+}
+
+// Réglages propres à la recherche : nouvelle partie, Chess960, nombre de variantes
+func (c *Client) prepare(req SearchRequest) error {
+	multiPV := req.MultiPV
+	if multiPV < 1 {
+		multiPV = 1
+	}
+	changed := false
+	if req.Chess960 != c.chess960 {
+		if err := c.send("setoption name UCI_Chess960 value %t", req.Chess960); err != nil {
+			return err
+		}
+		c.chess960, changed = req.Chess960, true
+	}
+	if multiPV != c.multiPV {
+		if err := c.send("setoption name MultiPV value %d", multiPV); err != nil {
+			return err
+		}
+		c.multiPV, changed = multiPV, true
+	}
+	if req.GameID == "" || req.GameID != c.lastGame {
+		if err := c.send("ucinewgame"); err != nil {
+			return err
+		}
+		c.lastGame, changed = req.GameID, true
+	}
+	if changed {
+		return c.isReady()
+	}
+	return nil
+}
+
+func goCommand(req SearchRequest) string {
+	var sb strings.Builder
+	sb.WriteString("go")
+	if req.WTime > 0 || req.BTime > 0 {
+		fmt.Fprintf(&sb, " wtime %d btime %d", req.WTime.Milliseconds(), req.BTime.Milliseconds())
+		if req.WInc > 0 || req.BInc > 0 {
+			fmt.Fprintf(&sb, " winc %d binc %d", req.WInc.Milliseconds(), req.BInc.Milliseconds())
+		}
+	}
+	if req.MoveTime > 0 {
+		fmt.Fprintf(&sb, " movetime %d", req.MoveTime.Milliseconds())
+	}
+	if req.Depth > 0 {
+		fmt.Fprintf(&sb, " depth %d", req.Depth)
+	}
+	if req.Nodes > 0 {
+		fmt.Fprintf(&sb, " nodes %d", req.Nodes)
+	}
+	if sb.Len() == len("go") {
+		sb.WriteString(" infinite")
+	}
+	return sb.String()
+}
+
+// Garder la dernière ligne de chaque variante (multipv 1, 2...)
+func setLine(lines []Info, info Info) []Info {
+	index := info.MultiPV - 1
+	if index < 0 {
+		index = 0
+	}
+	for len(lines) <= index {
+		lines = append(lines, Info{})
+	}
+	lines[index] = info
+	return lines
+}
+
+// Arrêter proprement le moteur, ou le tuer s'il ne répond pas
+func (c *Client) Close() {
+	c.send("quit")
+	c.stdin.Close()
+	select {
+	case <-c.exited:
+	case <-time.After(quitGrace):
+		c.kill()
+	}
+}
+
+func (c *Client) kill() {
+	if c.cmd.Process != nil {
+		if err := c.cmd.Process.Kill(); err != nil && c.Alive() {
+			log.Printf("Failed to kill uci engine %s: %v", c.path, err)
+		}
+	}
+}
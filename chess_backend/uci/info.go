@@ -0,0 +1,96 @@
+package uci
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Évaluation annoncée par le moteur, du point de vue du camp au trait
+type Score struct {
+	CP         int  `json:"cp"`
+	Mate       int  `json:"mate,omitempty"` // coups avant le mat (négatif si le camp au trait est maté)
+	IsMate     bool `json:"isMate"`
+	LowerBound bool `json:"lowerBound,omitempty"`
+	UpperBound bool `json:"upperBound,omitempty"`
+}
+
+// Ligne "info" d'une recherche en cours
+type Info struct {
+	Depth    int           `json:"depth"`
+	SelDepth int           `json:"selDepth,omitempty"`
+	MultiPV  int           `json:"multiPv"`
+	Score    Score         `json:"score"`
+	Nodes    uint64        `json:"nodes"`
+	NPS      uint64        `json:"nps,omitempty"`
+	Time     time.Duration `json:"time"`
+	PV       []string      `json:"pv"`
+}
+
+// Analyser une ligne "info" ; false si elle ne porte pas d'évaluation
+// (chaînes de caractères, coup en cours de recherche...)
+func ParseInfo(line string) (Info, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return Info{}, false
+	}
+
+	info := Info{MultiPV: 1}
+	hasScore := false
+	for i := 1; i < len(fields); i++ {
+		next := func() string {
+			if i+1 < len(fields) {
+				i++
+				return fields[i]
+			}
+			return ""
+		}
+		switch fields[i] {
+		case "depth":
+			info.Depth, _ = strconv.Atoi(next())
+		case "seldepth":
+			info.SelDepth, _ = strconv.Atoi(next())
+		case "multipv":
+			info.MultiPV, _ = strconv.Atoi(next())
+		case "nodes":
+			info.Nodes, _ = strconv.ParseUint(next(), 10, 64)
+		case "nps":
+			info.NPS, _ = strconv.ParseUint(next(), 10, 64)
+		case "time":
+			ms, _ := strconv.Atoi(next())
+			info.Time = time.Duration(ms) * time.Millisecond
+		case "score":
+			hasScore = true
+			switch next() {
+			case "cp":
+				info.Score.CP, _ = strconv.Atoi(next())
+			case "mate":
+				info.Score.IsMate = true
+				info.Score.Mate, _ = strconv.Atoi(next())
+			}
+		case "lowerbound":
+			info.Score.LowerBound = true
+		case "upperbound":
+			info.Score.UpperBound = true
+		case "pv":
+			// Les coups de la variante principale vont jusqu'à la fin de la ligne
+			info.PV = append([]string{}, fields[i+1:]...)
+			i = len(fields)
+		case "string":
+			return Info{}, false
+		}
+	}
+	return info, hasScore
+}
+
+// Analyser une ligne "bestmove <coup> [ponder <coup>]"
+func parseBestMove(line string) (best, ponder string) {
+	fields := strings.Fields(line)
+	if len(fields) >= 2 {
+		best = fields[1]
+	}
+	if len(fields) >= 4 && fields[2] == "ponder" {
+		ponder = fields[3]
+	}
+	return best, ponder
+}
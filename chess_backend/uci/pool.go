@@ -0,0 +1,94 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"log"
+)
+
+// Pool de processus moteur partagés entre les rooms : au plus size moteurs
+// tournent en même temps, et un moteur arrêté est relancé au prochain usage
+type Pool struct {
+	path    string
+	options map[string]string
+	idle    chan *Client
+	slots   chan struct{}
+}
+
+func NewPool(path string, size int, options map[string]string) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	return &Pool{
+		path:    path,
+		options: options,
+		idle:    make(chan *Client, size),
+		slots:   make(chan struct{}, size),
+	}
+}
+
+// Obtenir un moteur libre, en lançant un nouveau processus si nécessaire
+func (p *Pool) Acquire(ctx context.Context) (*Client, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case c := <-p.idle:
+		if c.Alive() {
+			return c, nil
+		}
+		log.Printf("UCI engine %s exited while idle, restarting", p.path)
+	default:
+	}
+
+	c, err := Start(p.path, p.options)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return c, nil
+}
+
+// Rendre un moteur au pool ; un moteur arrêté est abandonné
+func (p *Pool) Release(c *Client) {
+	if c.Alive() {
+		p.idle <- c
+	} else {
+		c.Close()
+	}
+	<-p.slots
+}
+
+// Lancer une recherche sur un moteur du pool ; si le moteur plante pendant la recherche,
+// elle est relancée une fois sur un nouveau processus
+func (p *Pool) Search(ctx context.Context, req SearchRequest) (SearchResult, error) {
+	for attempt := 0; ; attempt++ {
+		c, err := p.Acquire(ctx)
+		if err != nil {
+			return SearchResult{}, err
+		}
+		result, err := c.Search(ctx, req)
+		p.Release(c)
+
+		if err == nil || attempt > 0 || ctx.Err() != nil ||
+			!(errors.Is(err, ErrEngineExited) || errors.Is(err, ErrTimeout)) {
+			return result, err
+		}
+		log.Printf("UCI engine %s crashed during search, restarting: %v", p.path, err)
+	}
+}
+
+// Arrêter tous les moteurs libres
+func (p *Pool) Close() {
+	for {
+		select {
+		case c := <-p.idle:
+			c.Close()
+		default:
+			return
+		}
+	}
+}
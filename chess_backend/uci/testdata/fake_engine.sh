@@ -0,0 +1,42 @@
+#!/bin/sh
+# Faux moteur UCI pour les tests : joue toujours e2e4 (ou e7e5 après un coup),
+# plante sur "go depth 99" et ne s'arrête que sur "stop" pour "go infinite"
+while read -r line; do
+	case "$line" in
+	uci)
+		echo "id name FakeEngine 1.0"
+		echo "id author Tests"
+		echo "option name MultiPV type spin default 1 min 1 max 10"
+		echo "uciok"
+		;;
+	isready)
+		echo "readyok"
+		;;
+	position*)
+		position="$line"
+		;;
+	"go depth 99"*)
+		exit 1
+		;;
+	"go infinite"*)
+		echo "info depth 1 score cp 10 nodes 20 pv e2e4"
+		;;
+	go*)
+		best="e2e4"
+		case "$position" in
+		*moves*) best="e7e5" ;;
+		esac
+		echo "info string thinking"
+		echo "info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 2000 time 10 pv $best"
+		echo "info depth 2 seldepth 3 multipv 1 score cp 35 nodes 400 nps 40000 time 10 pv $best g1f3"
+		echo "info depth 2 seldepth 3 multipv 2 score mate -3 nodes 400 nps 40000 time 10 pv d2d4"
+		echo "bestmove $best ponder g1f3"
+		;;
+	stop)
+		echo "bestmove e2e4"
+		;;
+	quit)
+		exit 0
+		;;
+	esac
+done
@@ -0,0 +1,145 @@
+package uci
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const fakeEngine = "testdata/fake_engine.sh"
+
+func TestParseInfo(t *testing.T) {
+	info, ok := ParseInfo("info depth 12 seldepth 18 multipv 2 score cp -35 upperbound nodes 123456 nps 987 time 250 pv e7e5 g1f3 b8c6")
+	if !ok {
+		t.Fatal("expected an info line with a score")
+	}
+	if info.Depth != 12 || info.SelDepth != 18 || info.MultiPV != 2 || info.Nodes != 123456 || info.NPS != 987 {
+		t.Errorf("unexpected counters: %+v", info)
+	}
+	if info.Score.CP != -35 || !info.Score.UpperBound || info.Score.IsMate {
+		t.Errorf("unexpected score: %+v", info.Score)
+	}
+	if info.Time != 250*time.Millisecond || len(info.PV) != 3 || info.PV[2] != "b8c6" {
+		t.Errorf("unexpected time or pv: %v %v", info.Time, info.PV)
+	}
+
+	info, ok = ParseInfo("info depth 5 score mate -2 pv h7h8")
+	if !ok || !info.Score.IsMate || info.Score.Mate != -2 || info.MultiPV != 1 {
+		t.Errorf("unexpected mate info: %+v", info)
+	}
+
+	if _, ok := ParseInfo("info string NNUE evaluation enabled"); ok {
+		t.Error("info string should not be parsed as an evaluation")
+	}
+	if _, ok := ParseInfo("info depth 3 currmove e2e4 currmovenumber 1"); ok {
+		t.Error("info without score should be ignored")
+	}
+}
+
+func TestGoCommand(t *testing.T) {
+	tests := []struct {
+		req  SearchRequest
+		want string
+	}{
+		{SearchRequest{WTime: 60 * time.Second, BTime: 59500 * time.Millisecond}, "go wtime 60000 btime 59500"},
+		{SearchRequest{WTime: time.Second, BTime: time.Second, WInc: 2 * time.Second, BInc: 2 * time.Second}, "go wtime 1000 btime 1000 winc 2000 binc 2000"},
+		{SearchRequest{MoveTime: 500 * time.Millisecond, Depth: 8}, "go movetime 500 depth 8"},
+		{SearchRequest{}, "go infinite"},
+	}
+	for _, test := range tests {
+		if got := goCommand(test.req); got != test.want {
+			t.Errorf("goCommand(%+v) = %q, want %q", test.req, got, test.want)
+		}
+	}
+}
+
+func TestClientSearch(t *testing.T) {
+	c, err := Start(fakeEngine, map[string]string{"Hash": "16"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Name != "FakeEngine 1.0" || c.Author != "Tests" {
+		t.Errorf("unexpected engine id: %q by %q", c.Name, c.Author)
+	}
+
+	result, err := c.Search(context.Background(), SearchRequest{GameID: "g1", WTime: time.Minute, BTime: time.Minute, MultiPV: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BestMove != "e2e4" || result.Ponder != "g1f3" {
+		t.Errorf("unexpected bestmove: %+v", result)
+	}
+	if len(result.Lines) != 2 || result.Lines[0].Score.CP != 35 || !result.Lines[1].Score.IsMate {
+		t.Errorf("unexpected lines: %+v", result.Lines)
+	}
+
+	result, err = c.Search(context.Background(), SearchRequest{GameID: "g1", Moves: []string{"e2e4"}, MoveTime: time.Second})
+	if err != nil || result.BestMove != "e7e5" {
+		t.Errorf("unexpected reply after a move: %+v, %v", result, err)
+	}
+}
+
+func TestClientStop(t *testing.T) {
+	c, err := Start(fakeEngine, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := c.Search(ctx, SearchRequest{})
+	if err != nil || result.BestMove != "e2e4" {
+		t.Errorf("stopped search should return the engine's move: %+v, %v", result, err)
+	}
+}
+
+func TestClientCrash(t *testing.T) {
+	c, err := Start(fakeEngine, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.Search(context.Background(), SearchRequest{Depth: 99}); !errors.Is(err, ErrEngineExited) {
+		t.Fatalf("expected ErrEngineExited, got %v", err)
+	}
+	<-c.exited
+	if c.Alive() {
+		t.Error("crashed engine should not be alive")
+	}
+}
+
+func TestPoolRestartsCrashedEngine(t *testing.T) {
+	pool := NewPool(fakeEngine, 1, nil)
+	defer pool.Close()
+
+	c, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Search(context.Background(), SearchRequest{Depth: 99})
+	<-c.exited
+	pool.Release(c)
+
+	// Le moteur planté est remplacé par un nouveau processus
+	result, err := pool.Search(context.Background(), SearchRequest{GameID: "g2", MoveTime: time.Second})
+	if err != nil || result.BestMove != "e2e4" {
+		t.Fatalf("pool should restart the engine: %+v, %v", result, err)
+	}
+
+	// Une seule place : la seconde acquisition attend la libération de la première
+	first, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the pool to be full, got %v", err)
+	}
+	pool.Release(first)
+}